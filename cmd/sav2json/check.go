@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// runCheck runs the check command, which reports dangling references in the gamestate of a save game.
+func runCheck(args []string) error {
+	fs := newFlagSet(
+		"check",
+		"<inputfile>",
+		"Checks the references between the collections of the gamestate of a Stellaris save game\n"+
+			"and reports all references to entities, which do not exist, e.g. a ship in a deleted fleet.",
+	)
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 1, 1)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	gamestate, err := loadSaveFile(a[0], stellaris.GamestateFile, opt)
+	if err != nil {
+		return err
+	}
+	dangling := stellaris.NewResolver(gamestate).DanglingReferences()
+	for _, d := range dangling {
+		fmt.Printf("DANGLING: %s\n", d)
+	}
+	if len(dangling) > 0 {
+		return fmt.Errorf("found %d dangling references", len(dangling))
+	}
+	fmt.Println("No dangling references found")
+	return nil
+}
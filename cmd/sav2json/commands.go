@@ -0,0 +1,118 @@
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/charset"
+)
+
+// command represents a sub command of the tool.
+type command struct {
+	description string
+	run         func(args []string) error
+}
+
+// commands contains all sub commands by name.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"check":    {"check a save game for dangling references", runCheck},
+		"csv":      {"export a collection of a save game as CSV file", runCSV},
+		"galaxy":   {"find paths, chokepoints and borders in the galaxy of a save game", runGalaxy},
+		"graph":    {"export the galaxy of a save game as graph file", runGraph},
+		"graphql":  {"serve a save game as GraphQL API", runGraphQL},
+		"index":    {"write an index of the save games in a directory", runIndex},
+		"info":     {"show information about save games", runInfo},
+		"map":      {"render the galaxy of a save game as SVG map", runMap},
+		"parquet":  {"export collections of save games as Parquet files", runParquet},
+		"report":   {"create reports about save games", runReport},
+		"schema":   {"infer a JSON Schema from a save game", runSchema},
+		"serve":    {"serve a save game as REST API", runServe},
+		"sqlite":   {"export a save game into a SQLite database", runSQLite},
+		"timeline": {"extract time series of metrics from save games", runTimeline},
+		"validate": {"validate a save game against a JSON Schema", runValidate},
+	}
+}
+
+// parseFlags represents the flags for parsing save files,
+// which are shared by all commands that parse the gamestate.
+// Commands which only read the small meta file, e.g. info and index, always parse strictly.
+type parseFlags struct {
+	encoding   *string
+	lenient    *bool
+	unbalanced *bool
+}
+
+// addParseFlags defines the flags for parsing save files on fs.
+func addParseFlags(fs *flag.FlagSet) *parseFlags {
+	return &parseFlags{
+		encoding:   fs.String("e", "auto", "character encoding of save files: auto, utf-8 or windows-1252"),
+		lenient:    fs.Bool("l", false, "lenient mode: report syntax errors and keep going"),
+		unbalanced: fs.Bool("u", false, "allow unbalanced brackets in save files"),
+	}
+}
+
+// options returns the options for parsing save files from the flags.
+func (pf *parseFlags) options() (options, error) {
+	enc, err := charset.Parse(*pf.encoding)
+	if err != nil {
+		return options{}, err
+	}
+	opt := options{
+		lenient:         *pf.lenient,
+		allowUnbalanced: *pf.unbalanced,
+		inputEncoding:   enc,
+	}
+	return opt, nil
+}
+
+// newFlagSet returns a new flag set for a command with a usage message.
+func newFlagSet(name, arguments, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sav2json %s [options] %s:\n\n%s\n\nOptions:\n", name, arguments, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses the arguments of a command and returns the positional arguments.
+// Other than the standard flag package it also accepts flags after positional arguments.
+// It exits with the usage message when there are less than min or more than max positional arguments.
+// A negative max allows any number of positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string, min, max int) []string {
+	var positional []string
+	for {
+		fs.Parse(args) // exits on error
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) < min || max >= 0 && len(positional) > max {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return positional
+}
+
+// loadSaveFile parses the file with the given name from a save game and returns it's contents.
+func loadSaveFile(source, name string, opt options) (map[string][]any, error) {
+	r, err := zip.OpenReader(source)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name == name {
+			return parseFile(f, opt)
+		}
+	}
+	return nil, fmt.Errorf("%s: no file named %s in save game", source, name)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/table"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// runCSV runs the csv command, which exports a collection of the gamestate as CSV file.
+func runCSV(args []string) error {
+	fs := newFlagSet(
+		"csv",
+		"<outputfile> <inputfile>",
+		"Exports a collection of the gamestate of a Stellaris save game as CSV file.\n"+
+			"Each entity becomes a row and nested values get dotted column names, e.g. \"budget.current_month.income.planet_jobs.energy\".",
+	)
+	collectionFlag := fs.String("c", "country", "dotted path of the collection to export, e.g. \"planets.planet\"")
+	fieldsFlag := fs.String("f", "", "comma separated list of columns to export, which can contain patterns like \"name.*\" (default all columns)")
+	joinFlag := fs.String("j", table.DefaultSeparator, "separator for joining multiple values")
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 2, 2)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	dest, source := a[0], a[1]
+	data, err := loadSaveFile(source, stellaris.GamestateFile, opt)
+	if err != nil {
+		return err
+	}
+	var columns []string
+	if *fieldsFlag != "" {
+		for _, c := range strings.Split(*fieldsFlag, ",") {
+			columns = append(columns, strings.TrimSpace(c))
+		}
+	}
+	t, err := table.New(data, *collectionFlag, table.Options{Columns: columns, Separator: *joinFlag})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Writing CSV file: %s\n", dest)
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := t.WriteCSV(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
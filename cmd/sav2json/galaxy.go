@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/galaxy"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// runGalaxy runs the galaxy command, which analyzes the hyperlane network of a save game.
+func runGalaxy(args []string) error {
+	fs := newFlagSet(
+		"galaxy",
+		"<analysis> <inputfile>",
+		"Analyzes the hyperlane network of the galaxy of a Stellaris save game. Available analyses:\n"+
+			"  path         shortest path between the systems -from and -to\n"+
+			"  jumps        jumps from the system -from or the capital of country -c to all systems\n"+
+			"  chokepoints  systems which disconnect parts of the galaxy, when they are blocked\n"+
+			"  borders      systems of countries, which are connected to systems of others (all countries or -c)\n"+
+			"Systems can be given by their ID or name.",
+	)
+	countryFlag := fs.Int("c", stellaris.NoID, "ID of a country")
+	fromFlag := fs.String("from", "", "start system")
+	toFlag := fs.String("to", "", "destination system")
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 2, 2)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	analysis, source := a[0], a[1]
+	if !slices.Contains([]string{"path", "jumps", "chokepoints", "borders"}, analysis) {
+		return fmt.Errorf("unknown analysis: %s", analysis)
+	}
+	data, err := loadSaveFile(source, stellaris.GamestateFile, opt)
+	if err != nil {
+		return err
+	}
+	gs := stellaris.NewGamestate(data)
+	g := galaxy.NewGraph(gs)
+	switch analysis {
+	case "path":
+		if *fromFlag == "" || *toFlag == "" {
+			return errors.New("path needs the systems -from and -to")
+		}
+		from, err := findSystem(gs, *fromFlag)
+		if err != nil {
+			return err
+		}
+		to, err := findSystem(gs, *toFlag)
+		if err != nil {
+			return err
+		}
+		path, ok := g.ShortestPath(from, to)
+		if !ok {
+			return fmt.Errorf("no path from %s to %s", systemLabel(gs, from), systemLabel(gs, to))
+		}
+		fmt.Printf("Path from %s to %s with %d jumps:\n", systemLabel(gs, from), systemLabel(gs, to), len(path)-1)
+		for _, id := range path {
+			fmt.Printf("  %s\n", systemLabel(gs, id))
+		}
+	case "jumps":
+		var from int
+		switch {
+		case *fromFlag != "":
+			from, err = findSystem(gs, *fromFlag)
+			if err != nil {
+				return err
+			}
+		case *countryFlag != stellaris.NoID:
+			var ok bool
+			from, ok = galaxy.CapitalSystem(gs, *countryFlag)
+			if !ok {
+				return fmt.Errorf("no capital found for country %d", *countryFlag)
+			}
+		default:
+			return errors.New("jumps needs a system with -from or a country with -c")
+		}
+		jumps := g.Jumps(from)
+		ids := make([]int, 0, len(gs.Systems))
+		for id := range gs.Systems {
+			ids = append(ids, id)
+		}
+		slices.SortFunc(ids, func(a, b int) int {
+			x, okA := jumps[a]
+			y, okB := jumps[b]
+			if !okA {
+				x = len(ids)
+			}
+			if !okB {
+				y = len(ids)
+			}
+			if x != y {
+				return x - y
+			}
+			return a - b
+		})
+		fmt.Printf("Jumps from %s:\n", systemLabel(gs, from))
+		for _, id := range ids {
+			n, ok := jumps[id]
+			s := strconv.Itoa(n)
+			if !ok {
+				s = "-"
+			}
+			fmt.Printf("  %3s  %s\n", s, systemLabel(gs, id))
+		}
+	case "chokepoints":
+		fmt.Println("Chokepoints:")
+		for _, id := range g.Chokepoints() {
+			fmt.Printf("  %s\n", systemLabel(gs, id))
+		}
+	case "borders":
+		var countryIDs []int
+		if *countryFlag != stellaris.NoID {
+			countryIDs = []int{*countryFlag}
+		} else {
+			for id := range gs.Countries {
+				countryIDs = append(countryIDs, id)
+			}
+			slices.Sort(countryIDs)
+		}
+		for _, id := range countryIDs {
+			borders := galaxy.BorderSystems(gs, g, id)
+			if len(borders) == 0 {
+				continue
+			}
+			fmt.Printf("Border systems of %s:\n", countryLabel(gs, id))
+			for _, s := range borders {
+				fmt.Printf("  %s\n", systemLabel(gs, s))
+			}
+		}
+	}
+	return nil
+}
+
+// findSystem returns the ID of a system from it's ID or name.
+func findSystem(gs *stellaris.Gamestate, s string) (int, error) {
+	if id, err := strconv.Atoi(s); err == nil {
+		if _, ok := gs.Systems[id]; ok {
+			return id, nil
+		}
+		return 0, fmt.Errorf("system not found: %s", s)
+	}
+	var ids []int
+	for id, x := range gs.Systems {
+		if strings.EqualFold(x.Name, s) {
+			ids = append(ids, id)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return 0, fmt.Errorf("system not found: %s", s)
+	case 1:
+		return ids[0], nil
+	}
+	slices.Sort(ids)
+	return 0, fmt.Errorf("system name is not unique: %s: please use one of the IDs %v", s, ids)
+}
+
+// systemLabel returns the name, ID and owner of a system.
+func systemLabel(gs *stellaris.Gamestate, id int) string {
+	s := gs.Systems[id]
+	label := fmt.Sprintf("%s (%d)", s.Name, id)
+	if c, ok := gs.Countries[s.OwnerID]; ok {
+		label += " - " + c.Name
+	}
+	return label
+}
+
+// countryLabel returns the name and ID of a country.
+func countryLabel(gs *stellaris.Gamestate, id int) string {
+	if c, ok := gs.Countries[id]; ok {
+		return fmt.Sprintf("%s (%d)", c.Name, id)
+	}
+	return fmt.Sprintf("#%d", id)
+}
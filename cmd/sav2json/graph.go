@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/galaxy"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// runGraph runs the graph command, which exports the hyperlane network of a save game as graph file.
+func runGraph(args []string) error {
+	fs := newFlagSet(
+		"graph",
+		"<outputfile> <inputfile>",
+		"Exports the galaxy of a Stellaris save game as graph file, e.g. for Gephi or NetworkX.\n"+
+			"Nodes are systems with their name, owner, star class and number of planets.\n"+
+			"Edges are hyperlanes, wormholes and gateways with their type and length.",
+	)
+	formatFlag := fs.String("f", "graphml", "format of the graph: "+strings.Join(galaxy.GraphFormats, ", "))
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 2, 2)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(galaxy.GraphFormats, *formatFlag) {
+		return fmt.Errorf("invalid format for graph: %s", *formatFlag)
+	}
+	dest, source := a[0], a[1]
+	data, err := loadSaveFile(source, stellaris.GamestateFile, opt)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Writing graph: %s\n", dest)
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := galaxy.WriteGraph(w, stellaris.NewGamestate(data), *formatFlag); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
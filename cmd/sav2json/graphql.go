@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/graphql"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// runGraphQL runs the graphql command, which serves the typed model of a save game as GraphQL API.
+func runGraphQL(args []string) error {
+	fs := newFlagSet(
+		"graphql",
+		"<inputfile>",
+		"Parses a Stellaris save game once and serves it's data as GraphQL API at /graphql.\n"+
+			"Queries can be sent with GET ?query={query} or POST with a JSON body.",
+	)
+	addrFlag := fs.String("a", "localhost:8080", "address to listen on")
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 1, 1)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	gamestate, err := loadSaveFile(a[0], stellaris.GamestateFile, opt)
+	if err != nil {
+		return err
+	}
+	h, err := graphql.NewHandler(stellaris.NewGamestate(gamestate))
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", h)
+	fmt.Printf("Serving GraphQL API at: http://%s/graphql\n", *addrFlag)
+	return http.ListenAndServe(*addrFlag, mux)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/index"
+)
+
+// runIndex runs the index command, which writes an index of all save games in a directory.
+func runIndex(args []string) error {
+	fs := newFlagSet(
+		"index",
+		"<directory>",
+		"Writes an index of all Stellaris save games in a directory and it's sub directories.\n"+
+			"The index contains the path, size and modification time of each file\n"+
+			"and the empire name, in-game date, game version, DLCs and ironman flag from it's meta file.",
+	)
+	formatFlag := fs.String("f", "json", "format of the index: json or sqlite")
+	outputFlag := fs.String("o", "", "output file (default \"index.json\" or \"index.db\")")
+	a := parseArgs(fs, args, 1, 1)
+	dest := *outputFlag
+	switch *formatFlag {
+	case "json":
+		if dest == "" {
+			dest = "index.json"
+		}
+	case "sqlite":
+		if dest == "" {
+			dest = "index.db"
+		}
+	default:
+		return fmt.Errorf("invalid format for index: %s", *formatFlag)
+	}
+	dir := a[0]
+	fmt.Printf("Scanning directory: %s\n", dir)
+	entries, err := index.Build(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Error != "" {
+			fmt.Printf("WARNING: %s: %s\n", e.Path, e.Error)
+		}
+	}
+	fmt.Printf("Writing index with %d save games: %s\n", len(entries), dest)
+	if *formatFlag == "sqlite" {
+		return index.WriteSQLite(dest, entries)
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := index.WriteJSON(w, entries); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// saveInfo represents the information about a save game file.
+type saveInfo struct {
+	Path string `json:"path"`
+	stellaris.SaveInfo
+}
+
+// runInfo runs the info command, which shows the information from the meta file of save games.
+func runInfo(args []string) error {
+	fs := newFlagSet(
+		"info",
+		"<inputfile> [<inputfile>...]",
+		"Shows the information about Stellaris save games, e.g. game version and in-game date.\n"+
+			"Only the meta file is read, which is fast even for large save games.",
+	)
+	jsonFlag := fs.Bool("j", false, "print the information as JSON")
+	a := parseArgs(fs, args, 1, -1)
+	var infos []saveInfo
+	var errs []error
+	for _, source := range a {
+		si, err := stellaris.ReadSaveInfo(source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s: %w", source, err))
+			continue
+		}
+		infos = append(infos, saveInfo{Path: source, SaveInfo: si})
+	}
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(infos); err != nil {
+			return err
+		}
+	} else {
+		for i, x := range infos {
+			if i > 0 {
+				fmt.Println()
+			}
+			printSaveInfo(x)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func printSaveInfo(x saveInfo) {
+	ironman := "no"
+	if x.Ironman {
+		ironman = "yes"
+	}
+	f := x.Flag
+	rows := [][2]string{
+		{"File", x.Path},
+		{"Name", x.Name},
+		{"Version", x.Version},
+		{"Date", x.Date},
+		{"Ironman", ironman},
+		{"Player portrait", x.PlayerPortrait},
+		{"Flag", fmt.Sprintf(
+			"%s/%s on %s/%s, colors: %s",
+			f.Icon.Category, f.Icon.File, f.Background.Category, f.Background.File, strings.Join(f.Colors, ", "),
+		)},
+		{"Fleets", fmt.Sprint(x.Fleets)},
+		{"Planets", fmt.Sprint(x.Planets)},
+		{"DLCs", strings.Join(x.RequiredDLCs, ", ")},
+	}
+	for _, r := range rows {
+		fmt.Printf("%-16s %s\n", r[0]+":", r[1])
+	}
+}
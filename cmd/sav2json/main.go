@@ -2,26 +2,45 @@ package main
 
 import (
 	"archive/zip"
-	"encoding/json"
+	"bufio"
+	"cmp"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/ErikKalkoken/stellaris-tool/internal/charset"
+	"github.com/ErikKalkoken/stellaris-tool/internal/format"
 	"github.com/ErikKalkoken/stellaris-tool/internal/parser"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
 )
 
 // Current version need to be injected via ldflags
 var Version = "?"
 
 func main() {
+	if len(os.Args) > 1 {
+		if c, ok := commands[os.Args[1]]; ok {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Printf("ERROR: %s\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
 	flag.Usage = myUsage
 	destFlag := flag.String("d", ".", "destination directory for output files")
+	formatFlag := flag.String("f", format.Default, "output format: "+strings.Join(format.Names(), ", "))
 	keepFlag := flag.Bool("k", false, "keep original data files")
+	pf := addParseFlags(flag.CommandLine)
+	resolveFlag := flag.Bool("r", false, "add the names of referenced entities to the gamestate")
 	sameFlag := flag.Bool("s", false, "create output files in same directory as source files")
 	versionFlag := flag.Bool("v", false, "show the current version")
+	writeEncodingFlag := flag.String("w", "utf-8", "character encoding of JSON files: utf-8 or windows-1252")
 	flag.Parse()
 	if *versionFlag {
 		fmt.Printf("sav2json %s\n", Version)
@@ -38,7 +57,25 @@ func main() {
 	} else {
 		dest = *destFlag
 	}
-	if err := processSaveFile(source, dest, *keepFlag); err != nil {
+	opt, err := pf.options()
+	if err != nil {
+		fmt.Printf("ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	outputEncoding, err := charset.Parse(*writeEncodingFlag)
+	if err != nil || outputEncoding == charset.Auto {
+		fmt.Printf("ERROR: invalid encoding for JSON files: %s\n", *writeEncodingFlag)
+		os.Exit(1)
+	}
+	encoder, err := format.New(*formatFlag, outputEncoding)
+	if err != nil {
+		fmt.Printf("ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	opt.keepDataFiles = *keepFlag
+	opt.inlineNames = *resolveFlag
+	opt.encoder = encoder
+	if err := processSaveFile(source, dest, opt); err != nil {
 		fmt.Printf("ERROR: %s\n", err)
 		os.Exit(1)
 	}
@@ -46,17 +83,36 @@ func main() {
 
 // myUsage writes a custom usage message to configured output stream.
 func myUsage() {
-	s := "Usage: sav2json [options] <inputfile>:\n\n" +
+	s := "Usage: sav2json [options] <inputfile>:\n" +
+		"       sav2json <command> [options] <arguments>:\n\n" +
 		"A tool for converting Stellaris save games into JSON.\n" +
 		"For more information please see: https://github.com/ErikKalkoken/stellaris-tool\n\n" +
-		"Options:\n"
+		"Commands:\n"
 	fmt.Fprint(flag.CommandLine.Output(), s)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %-10s %s\n", name, commands[name].description)
+	}
+	fmt.Fprint(flag.CommandLine.Output(), "\nUse \"sav2json <command> -h\" for more information about a command.\n\nOptions:\n")
 	flag.PrintDefaults()
 }
 
-// processSaveFile writes the contents of a Stellaris safe game file in JSON format to disk.
-// It will optionally also write the raw data files to disk, when keepDataFiles is true.
-func processSaveFile(source string, dest string, keepDataFiles bool) error {
+// options represents the options for processing a save file.
+type options struct {
+	keepDataFiles   bool // also write the raw data files to disk
+	lenient         bool // report syntax errors as warnings and write the best-effort result
+	allowUnbalanced bool // accept files with unbalanced brackets
+	inputEncoding   charset.Encoding
+	inlineNames     bool           // add the names of referenced entities to the gamestate
+	encoder         format.Encoder // encoder for the output format
+}
+
+// processSaveFile writes the contents of a Stellaris safe game file in the chosen output format to disk.
+func processSaveFile(source string, dest string, opt options) error {
 	r, err := zip.OpenReader(source)
 	if err != nil {
 		return err
@@ -64,8 +120,13 @@ func processSaveFile(source string, dest string, keepDataFiles bool) error {
 	defer r.Close()
 	var hasErrors bool
 	fmt.Printf("Processing save file: %s\n", source)
-	for _, f := range r.File {
-		if keepDataFiles {
+	// The meta file is processed first, so the information about the save is shown early.
+	files := slices.Clone(r.File)
+	slices.SortStableFunc(files, func(a, b *zip.File) int {
+		return cmp.Compare(fileOrder(a.Name), fileOrder(b.Name))
+	})
+	for _, f := range files {
+		if opt.keepDataFiles {
 			if err := writeData(dest, f); err != nil {
 				fmt.Printf("ERROR: Failed to write data file for %s: %s\n", f.Name, err)
 				hasErrors = true
@@ -73,14 +134,21 @@ func processSaveFile(source string, dest string, keepDataFiles bool) error {
 			}
 
 		}
-		data, err := parseFile(f)
+		data, err := parseFile(f, opt)
 		if err != nil {
 			fmt.Printf("ERROR: Failed to parse %s: %s\n", f.Name, err)
 			hasErrors = true
 			continue
 		}
-		if err := writeJSON(dest, f.Name, data); err != nil {
-			fmt.Printf("ERROR: Failed to write JSON for %s: %s\n", f.Name, err)
+		if f.Name == stellaris.MetaFile {
+			si := stellaris.NewSaveInfo(data)
+			fmt.Printf("Save game: %s, %s, %s\n", si.Name, si.Version, si.Date)
+		}
+		if f.Name == stellaris.GamestateFile && opt.inlineNames {
+			stellaris.NewResolver(data).InlineNames()
+		}
+		if err := writeOutput(dest, f.Name, data, opt.encoder); err != nil {
+			fmt.Printf("ERROR: Failed to write output for %s: %s\n", f.Name, err)
 			hasErrors = true
 			continue
 		}
@@ -91,8 +159,16 @@ func processSaveFile(source string, dest string, keepDataFiles bool) error {
 	return nil
 }
 
+// fileOrder returns the position of a file for processing it.
+func fileOrder(name string) int {
+	if name == stellaris.MetaFile {
+		return 0
+	}
+	return 1
+}
+
 // parseFile parses a zip file and returns it's contents.
-func parseFile(f *zip.File) (map[string][]any, error) {
+func parseFile(f *zip.File, opt options) (map[string][]any, error) {
 	r, err := f.Open()
 	if err != nil {
 		return nil, err
@@ -100,10 +176,16 @@ func parseFile(f *zip.File) (map[string][]any, error) {
 	defer r.Close()
 	fmt.Printf("Parsing file: %s\n", f.Name)
 	p := parser.NewParser(r)
+	p.Lenient = opt.lenient
+	p.AllowUnbalanced = opt.allowUnbalanced
+	p.Encoding = opt.inputEncoding
 	data, err := p.Parse()
 	if err != nil {
 		return nil, err
 	}
+	for _, d := range p.Diagnostics() {
+		fmt.Printf("WARNING: %s: %s\n", f.Name, d)
+	}
 	return data, nil
 }
 
@@ -125,16 +207,21 @@ func writeData(dir string, f *zip.File) error {
 	return err
 }
 
-// writeJSON writes the given data to disk.
-func writeJSON(dir string, name string, data map[string][]any) error {
-	y, err := json.MarshalIndent(data, "", "    ")
+// writeOutput writes the given data to disk with the given encoder.
+func writeOutput(dir string, name string, data map[string][]any, encoder format.Encoder) error {
+	p := fmt.Sprintf("%s/%s%s", dir, name, encoder.Extension())
+	fmt.Printf("Writing output: %s\n", p)
+	f, err := os.Create(p)
 	if err != nil {
 		return err
 	}
-	p := fmt.Sprintf("%s/%s.json", dir, name)
-	fmt.Printf("Writing JSON: %s\n", p)
-	if err := os.WriteFile(p, y, 0644); err != nil {
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := encoder.Encode(w, data); err != nil {
 		return err
 	}
-	return nil
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
 }
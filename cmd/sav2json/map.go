@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/galaxy"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// runMap runs the map command, which renders the galaxy of a save game as SVG map.
+func runMap(args []string) error {
+	fs := newFlagSet(
+		"map",
+		"<outputfile> <inputfile>",
+		"Renders the galaxy of a Stellaris save game as SVG map with systems, hyperlanes\n"+
+			"and the territory of countries in the colors of their flags.",
+	)
+	labelsFlag := fs.Bool("n", false, "show the names of systems")
+	widthFlag := fs.Int("w", galaxy.DefaultWidth, "width of the map in pixels")
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 2, 2)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	dest, source := a[0], a[1]
+	data, err := loadSaveFile(source, stellaris.GamestateFile, opt)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Writing map: %s\n", dest)
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := galaxy.WriteSVG(f, stellaris.NewGamestate(data), galaxy.SVGOptions{Width: *widthFlag, Labels: *labelsFlag}); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/parquet"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// runParquet runs the parquet command, which exports collections of save games as Parquet files.
+func runParquet(args []string) error {
+	var names []string
+	for _, c := range stellaris.Collections {
+		names = append(names, c.Name)
+	}
+	fs := newFlagSet(
+		"parquet",
+		"<outputdir> <inputfile> [<inputfile>...]",
+		"Exports collections of the gamestate of Stellaris save games as Parquet files.\n"+
+			"Each collection is written into it's own directory with partitions for the name and date of the save,\n"+
+			"e.g. countries/save=<name>/date=<date>/data.parquet.",
+	)
+	collectionsFlag := fs.String("c", "", "comma separated list of collections to export: "+strings.Join(names, ", ")+" (default all)")
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 2, -1)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	collections := stellaris.Collections
+	if *collectionsFlag != "" {
+		collections = nil
+		for _, n := range strings.Split(*collectionsFlag, ",") {
+			c, ok := stellaris.FindCollection(strings.TrimSpace(n))
+			if !ok {
+				return fmt.Errorf("unknown collection: %s", n)
+			}
+			collections = append(collections, c)
+		}
+	}
+	dest := a[0]
+	var hasErrors bool
+	for _, source := range a[1:] {
+		if err := exportParquet(dest, source, collections, opt); err != nil {
+			fmt.Printf("ERROR: Failed to export %s: %s\n", source, err)
+			hasErrors = true
+		}
+	}
+	if hasErrors {
+		return errors.New("processing failed with errors")
+	}
+	return nil
+}
+
+// exportParquet exports the collections of a save game into the directory dest.
+func exportParquet(dest, source string, collections []stellaris.Collection, opt options) error {
+	fmt.Printf("Processing save file: %s\n", source)
+	meta, err := loadSaveFile(source, stellaris.MetaFile, opt)
+	if err != nil {
+		return err
+	}
+	gamestate, err := loadSaveFile(source, stellaris.GamestateFile, opt)
+	if err != nil {
+		return err
+	}
+	paths, err := parquet.Export(dest, meta, gamestate, collections)
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		fmt.Printf("Written Parquet file: %s\n", p)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/report"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// reportExtensions are the file extensions for the report formats.
+var reportExtensions = map[string]string{"text": "txt", "markdown": "md", "html": "html", "json": "json"}
+
+// reportFormats are the supported formats of each report. The first format is the default.
+var reportFormats = map[string][]string{
+	"empires": report.Formats,
+	"wars":    {"markdown", "json"},
+}
+
+// runReport runs the report command, which creates reports about save games.
+func runReport(args []string) error {
+	fs := newFlagSet(
+		"report",
+		"<report> <inputfile> [<inputfile>...]",
+		"Creates a report about a Stellaris save game. Available reports:\n"+
+			"  empires   ranked table of all empires with their economy, military, technology, planets and diplomacy\n"+
+			"  wars      wars with their participants, war goals, war exhaustion and battles,\n"+
+			"            which can combine many saves of a campaign, e.g. autosaves.\n"+
+			"            War results are not included, since the save does not record them.",
+	)
+	allFlag := fs.Bool("a", false, "include all countries, e.g. pirates, instead of only regular empires")
+	formatFlag := fs.String("f", "", "format of the report: "+strings.Join(report.Formats, ", ")+" for empires, markdown or json for wars (default \"text\" or \"markdown\")")
+	outputFlag := fs.String("o", "", "output file (default \"<report>\" with the extension of the format)")
+	sortFlag := fs.String("s", "score", "rank empires by: "+strings.Join(report.EmpireSortKeys, ", "))
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 2, -1)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	name, sources := a[0], a[1:]
+	formats, ok := reportFormats[name]
+	if !ok {
+		return fmt.Errorf("unknown report: %s", name)
+	}
+	format := *formatFlag
+	if format == "" {
+		format = formats[0]
+	}
+	if !slices.Contains(formats, format) {
+		return fmt.Errorf("invalid format for %s report: %s", name, format)
+	}
+	if name == "empires" && len(sources) > 1 {
+		return errors.New("empires report needs exactly one save game")
+	}
+	dest := *outputFlag
+	if dest == "" {
+		dest = name + "." + reportExtensions[format]
+	}
+	var write func(w *bufio.Writer) error
+	switch name {
+	case "empires":
+		data, err := loadSaveFile(sources[0], stellaris.GamestateFile, opt)
+		if err != nil {
+			return err
+		}
+		empires, err := report.Empires(stellaris.NewGamestate(data), report.EmpiresOptions{SortBy: *sortFlag, All: *allFlag})
+		if err != nil {
+			return err
+		}
+		write = func(w *bufio.Writer) error {
+			return report.EmpiresTable(empires).Write(w, format)
+		}
+	case "wars":
+		h := report.NewWarHistory()
+		for _, source := range sources {
+			fmt.Printf("Processing save file: %s\n", source)
+			data, err := loadSaveFile(source, stellaris.GamestateFile, opt)
+			if err != nil {
+				return err
+			}
+			h.Add(stellaris.NewGamestate(data))
+		}
+		wars := h.Wars()
+		write = func(w *bufio.Writer) error {
+			if format == "json" {
+				return report.WriteWarsJSON(w, wars)
+			}
+			return report.WriteWarsMarkdown(w, wars)
+		}
+	}
+	fmt.Printf("Writing report: %s\n", dest)
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/schema"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// runSchema runs the schema command, which infers a JSON Schema from a save game.
+func runSchema(args []string) error {
+	fs := newFlagSet(
+		"schema",
+		"<outputfile> <inputfile>",
+		"Infers a JSON Schema for the JSON output of a file from a Stellaris save game.\n"+
+			"The schema describes the observed types of all values, whether keys are repeated,\n"+
+			"which objects are collections keyed by ID, the range of numbers and examples.",
+	)
+	nameFlag := fs.String("n", stellaris.GamestateFile, "name of the file in the save game: gamestate or meta")
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 2, 2)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	dest, source := a[0], a[1]
+	meta, err := loadSaveFile(source, stellaris.MetaFile, opt)
+	if err != nil {
+		return err
+	}
+	data := meta
+	if *nameFlag != stellaris.MetaFile {
+		data, err = loadSaveFile(source, *nameFlag, opt)
+		if err != nil {
+			return err
+		}
+	}
+	s := schema.Infer(data)
+	s.Title = *nameFlag
+	s.GameVersion = stellaris.NewSaveInfo(meta).Version
+	fmt.Printf("Writing schema: %s\n", dest)
+	return writeJSON(dest, s)
+}
+
+// writeJSON writes v as indented JSON file to path.
+func writeJSON(path string, v any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return f.Close()
+}
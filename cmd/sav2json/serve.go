@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/server"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// runServe runs the serve command, which serves the data of a save game as REST API.
+func runServe(args []string) error {
+	fs := newFlagSet(
+		"serve",
+		"<inputfile>",
+		"Parses a Stellaris save game once and serves it's data as REST API with JSON responses.\n"+
+			"Endpoints: /meta, /{collection}, /{collection}/{id} and /query?path={path}\n"+
+			"Collections can be filtered by the values of their entities, e.g. /planets?owner=0.",
+	)
+	addrFlag := fs.String("a", "localhost:8080", "address to listen on")
+	corsFlag := fs.String("cors", "", "allowed origin for cross-origin requests, e.g. \"*\" or \"http://localhost:3000\"")
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 1, 1)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	source := a[0]
+	meta, err := loadSaveFile(source, stellaris.MetaFile, opt)
+	if err != nil {
+		return err
+	}
+	gamestate, err := loadSaveFile(source, stellaris.GamestateFile, opt)
+	if err != nil {
+		return err
+	}
+	s := server.New(meta, gamestate)
+	s.AllowOrigin = *corsFlag
+	fmt.Printf("Serving save game at: http://%s\n", *addrFlag)
+	return http.ListenAndServe(*addrFlag, s)
+}
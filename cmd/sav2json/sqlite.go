@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/sqlite"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// runSQLite runs the sqlite command, which exports the gamestate of a save game into a SQLite database.
+func runSQLite(args []string) error {
+	fs := newFlagSet(
+		"sqlite",
+		"<outputfile> <inputfile>",
+		"Exports the gamestate of a Stellaris save game into a SQLite database.\n"+
+			"An existing database file will be replaced.",
+	)
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 2, 2)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	dest, source := a[0], a[1]
+	data, err := loadSaveFile(source, stellaris.GamestateFile, opt)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Writing database: %s\n", dest)
+	return sqlite.Export(dest, data)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/timeline"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// metricsFlag represents a flag for metrics, which can be repeated.
+type metricsFlag []string
+
+func (m *metricsFlag) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *metricsFlag) Set(s string) error {
+	for _, x := range strings.Split(s, ",") {
+		*m = append(*m, strings.TrimSpace(x))
+	}
+	return nil
+}
+
+// runTimeline runs the timeline command, which extracts time series of metrics from many save games.
+func runTimeline(args []string) error {
+	fs := newFlagSet(
+		"timeline",
+		"<inputfile> [<inputfile>...]",
+		"Extracts the values of metrics from Stellaris save games as time series ordered by the in-game date.\n"+
+			"Metrics are dotted paths in the gamestate, which can contain patterns,\n"+
+			"e.g. \"country.*.military_power\" for the military power of all countries.",
+	)
+	var metrics metricsFlag
+	fs.Var(&metrics, "metric", "dotted path of a metric, can be repeated or a comma separated list")
+	formatFlag := fs.String("f", "csv", "format of the time series: csv or json")
+	outputFlag := fs.String("o", "", "output file (default \"timeline.csv\" or \"timeline.json\")")
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 1, -1)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		return fmt.Errorf("invalid format for timeline: %s", *formatFlag)
+	}
+	if len(metrics) == 0 {
+		return errors.New("no metrics: please provide metrics with -metric")
+	}
+	dest := *outputFlag
+	if dest == "" {
+		dest = "timeline." + *formatFlag
+	}
+	tl, err := timeline.New(metrics)
+	if err != nil {
+		return err
+	}
+	var hasErrors bool
+	for _, source := range a {
+		if err := addSample(tl, source, opt); err != nil {
+			fmt.Printf("ERROR: Failed to process %s: %s\n", source, err)
+			hasErrors = true
+		}
+	}
+	fmt.Printf("Writing timeline with %d save games: %s\n", len(tl.Samples()), dest)
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if *formatFlag == "json" {
+		err = tl.WriteJSON(w)
+	} else {
+		err = tl.WriteCSV(w)
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if hasErrors {
+		return errors.New("processing failed with errors")
+	}
+	return nil
+}
+
+// addSample adds the values of the metrics from a save game to the timeline.
+func addSample(tl *timeline.Timeline, source string, opt options) error {
+	fmt.Printf("Processing save file: %s\n", source)
+	meta, err := loadSaveFile(source, stellaris.MetaFile, opt)
+	if err != nil {
+		return err
+	}
+	gamestate, err := loadSaveFile(source, stellaris.GamestateFile, opt)
+	if err != nil {
+		return err
+	}
+	tl.Add(source, meta, gamestate)
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/schema"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// runValidate runs the validate command, which checks the gamestate of a save game against a JSON Schema.
+func runValidate(args []string) error {
+	fs := newFlagSet(
+		"validate",
+		"<inputfile>",
+		"Validates the gamestate of a Stellaris save game against a JSON Schema and reports all violations.\n"+
+			"Without a schema file the bundled schema for the game version of the save is used.",
+	)
+	schemaFlag := fs.String("schema", "", "JSON Schema file to validate against (default bundled schema for the game version)")
+	pf := addParseFlags(fs)
+	a := parseArgs(fs, args, 1, 1)
+	opt, err := pf.options()
+	if err != nil {
+		return err
+	}
+	source := a[0]
+	meta, err := loadSaveFile(source, stellaris.MetaFile, opt)
+	if err != nil {
+		return err
+	}
+	var s *schema.Schema
+	if *schemaFlag != "" {
+		s, err = loadSchema(*schemaFlag)
+	} else {
+		s, err = schema.Bundled(stellaris.NewSaveInfo(meta).Version)
+		if err != nil {
+			err = fmt.Errorf("%w: please provide a schema file with -schema", err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	gamestate, err := loadSaveFile(source, stellaris.GamestateFile, opt)
+	if err != nil {
+		return err
+	}
+	violations := s.Validate(gamestate)
+	for _, v := range violations {
+		fmt.Printf("VIOLATION: %s\n", v)
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("found %d violations", len(violations))
+	}
+	fmt.Println("No violations found")
+	return nil
+}
+
+func loadSchema(path string) (*schema.Schema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return schema.Load(f)
+}
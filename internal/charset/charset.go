@@ -0,0 +1,82 @@
+// Package charset provides the character encodings used in Paradox files.
+package charset
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Encoding represents a character encoding.
+type Encoding uint
+
+const (
+	// Auto decodes input as UTF-8 and falls back to Windows-1252 for invalid bytes.
+	Auto Encoding = iota
+	UTF8
+	Windows1252
+)
+
+var encodingNames = map[Encoding]string{
+	Auto:        "auto",
+	UTF8:        "utf-8",
+	Windows1252: "windows-1252",
+}
+
+func (e Encoding) String() string {
+	s, ok := encodingNames[e]
+	if !ok {
+		return "?"
+	}
+	return s
+}
+
+// Parse returns the encoding for a name, e.g. "utf-8".
+func Parse(name string) (Encoding, error) {
+	name = strings.ToLower(name)
+	for e, s := range encodingNames {
+		if s == name {
+			return e, nil
+		}
+	}
+	switch name {
+	case "utf8":
+		return UTF8, nil
+	case "cp1252", "latin-1", "latin1", "iso-8859-1":
+		return Windows1252, nil
+	}
+	return 0, fmt.Errorf("unknown encoding: %s", name)
+}
+
+// Byte Order Mark of UTF-8 encoded input
+var BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Characters for the range 0x80 - 0x9F of Windows-1252.
+// Undefined bytes are mapped to the C1 control code with the same value.
+var windows1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// DecodeWindows1252 returns the character for a byte in Windows-1252.
+func DecodeWindows1252(b byte) rune {
+	if b >= 0x80 && b <= 0x9F {
+		return windows1252[b-0x80]
+	}
+	return rune(b) // identical with ISO-8859-1
+}
+
+// EncodeWindows1252 returns the byte for a character in Windows-1252
+// and reports whether the character can be represented.
+func EncodeWindows1252(r rune) (byte, bool) {
+	if r < 0x80 || r >= 0xA0 && r <= 0xFF {
+		return byte(r), true
+	}
+	for i, x := range windows1252 {
+		if x == r {
+			return byte(0x80 + i), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,93 @@
+package charset_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/charset"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		in   string
+		want charset.Encoding
+	}{
+		{"auto", charset.Auto},
+		{"utf-8", charset.UTF8},
+		{"UTF8", charset.UTF8},
+		{"windows-1252", charset.Windows1252},
+		{"cp1252", charset.Windows1252},
+		{"latin1", charset.Windows1252},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := charset.Parse(tc.in)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+	t.Run("should return error for unknown encodings", func(t *testing.T) {
+		_, err := charset.Parse("ebcdic")
+		assert.Error(t, err)
+	})
+}
+
+func TestWindows1252(t *testing.T) {
+	cases := []struct {
+		b byte
+		r rune
+	}{
+		{'A', 'A'},
+		{0x80, '€'},
+		{0x8C, 'Œ'},
+		{0x9F, 'Ÿ'},
+		{0xE7, 'ç'},
+		{0xFC, 'ü'},
+		{0x81, 0x81},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.r), func(t *testing.T) {
+			assert.Equal(t, tc.r, charset.DecodeWindows1252(tc.b))
+			b, ok := charset.EncodeWindows1252(tc.r)
+			if assert.True(t, ok) {
+				assert.Equal(t, tc.b, b)
+			}
+		})
+	}
+	t.Run("should report characters which can not be encoded", func(t *testing.T) {
+		_, ok := charset.EncodeWindows1252('Ж')
+		assert.False(t, ok)
+	})
+}
+
+func TestJSONWriter(t *testing.T) {
+	t.Run("can write JSON in Windows-1252", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := charset.NewJSONWriter(&buf, charset.Windows1252)
+		_, err := w.Write([]byte(`{"name":"François Œuvre €","leader":"Жора 🚀"}`))
+		if assert.NoError(t, err) {
+			want := "{\"name\":\"Fran\xE7ois \x8Cuvre \x80\",\"leader\":\"\\u0416\\u043e\\u0440\\u0430 \\ud83d\\ude80\"}"
+			assert.Equal(t, want, buf.String())
+		}
+	})
+	t.Run("can handle characters split across writes", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := charset.NewJSONWriter(&buf, charset.Windows1252)
+		in := []byte(`"Kürsk"`)
+		for _, b := range in {
+			_, err := w.Write([]byte{b})
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, "\"K\xFCrsk\"", buf.String())
+	})
+	t.Run("should not change UTF-8", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := charset.NewJSONWriter(&buf, charset.UTF8)
+		_, err := w.Write([]byte(`"Kürsk"`))
+		if assert.NoError(t, err) {
+			assert.Equal(t, `"Kürsk"`, buf.String())
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package charset
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// jsonWriter converts UTF-8 encoded JSON into Windows-1252.
+type jsonWriter struct {
+	w       io.Writer
+	partial []byte // incomplete character from the previous write
+}
+
+// NewJSONWriter returns a writer which converts UTF-8 encoded JSON into the given encoding.
+// Characters which can not be represented in the encoding are written as JSON unicode escapes,
+// which is lossless, because JSON can only contain non-ASCII characters within strings.
+func NewJSONWriter(w io.Writer, enc Encoding) io.Writer {
+	if enc != Windows1252 {
+		return w
+	}
+	return &jsonWriter{w: w}
+}
+
+func (w *jsonWriter) Write(p []byte) (int, error) {
+	b := append(w.partial, p...)
+	out := make([]byte, 0, len(b))
+	for len(b) > 0 {
+		if !utf8.FullRune(b) {
+			break
+		}
+		r, size := utf8.DecodeRune(b)
+		if x, ok := EncodeWindows1252(r); ok {
+			out = append(out, x)
+		} else if r > 0xFFFF {
+			r1, r2 := utf16.EncodeRune(r)
+			out = fmt.Appendf(out, `\u%04x\u%04x`, r1, r2)
+		} else {
+			out = fmt.Appendf(out, `\u%04x`, r)
+		}
+		b = b[size:]
+	}
+	w.partial = append(w.partial[:0:0], b...)
+	if _, err := w.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,139 @@
+package format
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/charset"
+)
+
+// JSON encodes data as indented JSON.
+type JSON struct {
+	Encoding charset.Encoding // character encoding of the output
+}
+
+func (e *JSON) setEncoding(enc charset.Encoding) {
+	e.Encoding = enc
+}
+
+func (e *JSON) Encode(w io.Writer, data map[string][]any) error {
+	enc := json.NewEncoder(charset.NewJSONWriter(w, e.Encoding))
+	enc.SetIndent("", "    ")
+	return enc.Encode(data)
+}
+
+func (*JSON) Extension() string {
+	return ".json"
+}
+
+// YAML encodes data as YAML, which is easier to read for humans.
+type YAML struct{}
+
+func (YAML) Encode(w io.Writer, data map[string][]any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func (YAML) Extension() string {
+	return ".yaml"
+}
+
+// TOML encodes data as TOML.
+// TOML has no null value, so values which are none are omitted.
+type TOML struct{}
+
+func (TOML) Encode(w io.Writer, data map[string][]any) error {
+	return toml.NewEncoder(w).Encode(withoutNulls(data))
+}
+
+func (TOML) Extension() string {
+	return ".toml"
+}
+
+// MessagePack encodes data as compact binary MessagePack.
+type MessagePack struct{}
+
+func (MessagePack) Encode(w io.Writer, data map[string][]any) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetSortMapKeys(true)
+	return enc.Encode(data)
+}
+
+func (MessagePack) Extension() string {
+	return ".msgpack"
+}
+
+// CBOR encodes data as compact binary CBOR.
+// Floats are stored in the smallest size which preserves their value.
+type CBOR struct{}
+
+func (CBOR) Encode(w io.Writer, data map[string][]any) error {
+	opts := cbor.EncOptions{Sort: cbor.SortCanonical, ShortestFloat: cbor.ShortestFloat16}
+	em, err := opts.EncMode()
+	if err != nil {
+		return err
+	}
+	return em.NewEncoder(w).Encode(data)
+}
+
+func (CBOR) Extension() string {
+	return ".cbor"
+}
+
+// withoutNulls returns a copy of data with all nil values removed.
+func withoutNulls(data map[string][]any) map[string][]any {
+	r := make(map[string][]any, len(data))
+	for k, vv := range data {
+		r[k] = withoutNullsSlice(vv)
+	}
+	return r
+}
+
+func withoutNullsSlice(vv []any) []any {
+	r := make([]any, 0, len(vv))
+	for _, v := range vv {
+		switch x := v.(type) {
+		case nil:
+			continue
+		case map[string][]any:
+			r = append(r, withoutNulls(x))
+		case []map[string][]any:
+			oo := make([]map[string][]any, len(x))
+			for i, o := range x {
+				oo[i] = withoutNulls(o)
+			}
+			r = append(r, oo)
+		case []any:
+			r = append(r, withoutNullsSlice(x))
+		default:
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// CompactJSON encodes data as JSON without any whitespace.
+type CompactJSON struct {
+	Encoding charset.Encoding // character encoding of the output
+}
+
+func (e *CompactJSON) setEncoding(enc charset.Encoding) {
+	e.Encoding = enc
+}
+
+func (e *CompactJSON) Encode(w io.Writer, data map[string][]any) error {
+	return json.NewEncoder(charset.NewJSONWriter(w, e.Encoding)).Encode(data)
+}
+
+func (*CompactJSON) Extension() string {
+	return ".json"
+}
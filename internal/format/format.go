@@ -0,0 +1,59 @@
+// Package format provides encoders for writing parsed save files in different output formats.
+package format
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/charset"
+)
+
+// Encoder writes parsed data in a specific output format.
+type Encoder interface {
+	// Encode writes data to w.
+	Encode(w io.Writer, data map[string][]any) error
+	// Extension returns the file extension for the format, e.g. ".json".
+	Extension() string
+}
+
+// Default is the name of the default output format.
+const Default = "json"
+
+// encoders maps the names of all supported formats to their encoder constructors.
+var encoders = map[string]func() Encoder{
+	"json":         func() Encoder { return &JSON{} },
+	"json-compact": func() Encoder { return &CompactJSON{} },
+	"ndjson":       func() Encoder { return &NDJSON{} },
+	"yaml":         func() Encoder { return YAML{} },
+	"toml":         func() Encoder { return TOML{} },
+	"msgpack":      func() Encoder { return MessagePack{} },
+	"cbor":         func() Encoder { return CBOR{} },
+}
+
+// Names returns the names of all supported formats in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(encoders))
+	for n := range encoders {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// New returns the encoder for a format name.
+// The character encoding enc is only supported by JSON based formats,
+// all other formats always use UTF-8.
+func New(name string, enc charset.Encoding) (Encoder, error) {
+	f, ok := encoders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format: %s", name)
+	}
+	e := f()
+	if x, ok := e.(interface{ setEncoding(charset.Encoding) }); ok {
+		x.setEncoding(enc)
+	} else if enc == charset.Windows1252 {
+		return nil, fmt.Errorf("output format %s does not support encoding %s", name, enc)
+	}
+	return e, nil
+}
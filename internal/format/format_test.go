@@ -0,0 +1,174 @@
+package format_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/charset"
+	"github.com/ErikKalkoken/stellaris-tool/internal/format"
+)
+
+var data = map[string][]any{
+	"name":    {"Kürsk Ünion"},
+	"version": {3.0, 4.5},
+	"ironman": {true},
+	"flag":    {map[string][]any{"colors": {[]string{"red", "blue"}}}},
+	"coords":  {[]float64{1.5, -2}},
+	"mixed":   {[]any{1.0, "two", true}},
+	"ships":   {[]map[string][]any{{"hp": {100.0}}, {"hp": {0.25}}}},
+	"empty":   {},
+}
+
+func TestNew(t *testing.T) {
+	t.Run("should list all formats", func(t *testing.T) {
+		assert.Equal(t, []string{"cbor", "json", "json-compact", "msgpack", "ndjson", "toml", "yaml"}, format.Names())
+	})
+	t.Run("should return error for unknown formats", func(t *testing.T) {
+		_, err := format.New("xml", charset.UTF8)
+		assert.Error(t, err)
+	})
+	t.Run("should support Windows-1252 for JSON", func(t *testing.T) {
+		e, err := format.New("json", charset.Windows1252)
+		if assert.NoError(t, err) {
+			var buf bytes.Buffer
+			err := e.Encode(&buf, map[string][]any{"name": {"Kürsk"}})
+			if assert.NoError(t, err) {
+				assert.Contains(t, buf.String(), "K\xFCrsk")
+			}
+		}
+	})
+	t.Run("should not support Windows-1252 for other formats", func(t *testing.T) {
+		_, err := format.New("yaml", charset.Windows1252)
+		assert.Error(t, err)
+	})
+}
+
+func TestEncoders(t *testing.T) {
+	cases := []struct {
+		name   string
+		ext    string
+		decode func(b []byte) (any, error)
+	}{
+		{"json", ".json", func(b []byte) (any, error) {
+			var x map[string]any
+			err := json.Unmarshal(b, &x)
+			return x, err
+		}},
+		{"json-compact", ".json", func(b []byte) (any, error) {
+			if bytes.Count(b, []byte("\n")) != 1 {
+				return nil, fmt.Errorf("not compact")
+			}
+			var x map[string]any
+			err := json.Unmarshal(b, &x)
+			return x, err
+		}},
+		{"yaml", ".yaml", func(b []byte) (any, error) {
+			var x map[string]any
+			err := yaml.Unmarshal(b, &x)
+			return x, err
+		}},
+		{"toml", ".toml", func(b []byte) (any, error) {
+			var x map[string]any
+			err := toml.Unmarshal(b, &x)
+			return x, err
+		}},
+		{"msgpack", ".msgpack", func(b []byte) (any, error) {
+			var x map[string]any
+			err := msgpack.Unmarshal(b, &x)
+			return x, err
+		}},
+		{"cbor", ".cbor", func(b []byte) (any, error) {
+			dm, err := cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any{})}.DecMode()
+			if err != nil {
+				return nil, err
+			}
+			var x map[string]any
+			err = dm.Unmarshal(b, &x)
+			return x, err
+		}},
+	}
+	for _, tc := range cases {
+		t.Run("can encode "+tc.name, func(t *testing.T) {
+			e, err := format.New(tc.name, charset.UTF8)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.Equal(t, tc.ext, e.Extension())
+			var buf bytes.Buffer
+			if err := e.Encode(&buf, data); !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			got, err := tc.decode(buf.Bytes())
+			if assert.NoError(t, err) {
+				assert.Equal(t, normalize(t, data), normalize(t, got))
+			}
+		})
+	}
+	t.Run("should omit null values in TOML", func(t *testing.T) {
+		e, err := format.New("toml", charset.UTF8)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		var buf bytes.Buffer
+		err = e.Encode(&buf, map[string][]any{"alpha": {nil, 1.0}, "bravo": {nil}})
+		if assert.NoError(t, err) {
+			var got map[string]any
+			if assert.NoError(t, toml.Unmarshal(buf.Bytes(), &got)) {
+				assert.Equal(t, map[string]any{"alpha": []any{1.0}, "bravo": []any{}}, normalize(t, got))
+			}
+		}
+	})
+}
+
+func TestNDJSON(t *testing.T) {
+	data := map[string][]any{
+		"version": {"Andromeda v3.12.5"},
+		"country": {map[string][]any{
+			"10": {map[string][]any{"name": {"Beta"}}},
+			"2":  {map[string][]any{"name": {"Alpha"}}},
+			"3":  {nil},
+		}},
+		"planets": {map[string][]any{
+			"planet": {map[string][]any{"5": {map[string][]any{"size": {16.0}}}}},
+		}},
+		"flags": {map[string][]any{"alpha": {1.0}}},
+	}
+	e, err := format.New("ndjson", charset.UTF8)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, ".ndjson", e.Extension())
+	var buf bytes.Buffer
+	if assert.NoError(t, e.Encode(&buf, data)) {
+		want := `{"section":"country","id":"2","value":{"name":["Alpha"]}}
+{"section":"country","id":"3","value":null}
+{"section":"country","id":"10","value":{"name":["Beta"]}}
+{"section":"flags","value":{"alpha":[1]}}
+{"section":"planets.planet","id":"5","value":{"size":[16]}}
+{"section":"version","value":"Andromeda v3.12.5"}
+`
+		assert.Equal(t, want, buf.String())
+	}
+}
+
+// normalize returns v with all numbers as float64 and all collections as generic types.
+func normalize(t *testing.T, v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var x any
+	if err := json.Unmarshal(b, &x); err != nil {
+		t.Fatal(err)
+	}
+	return x
+}
@@ -0,0 +1,46 @@
+package format
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/charset"
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+)
+
+// NDJSON encodes data as newline delimited JSON with one line per top-level entity,
+// so it can be loaded line by line into tools like DuckDB or BigQuery.
+//
+// Each line is an object with these fields:
+//   - section: key of the top-level section, e.g. "country"
+//   - id: ID of the entity (only for collections of entities)
+//   - value: the entity
+//
+// Collections of entities are sections which are keyed by ID, e.g. country={ 0={...} },
+// or contain such collections, e.g. planets={ planet={ 0={...} } } with the section "planets.planet".
+// Every other top-level value is written as one line.
+type NDJSON struct {
+	Encoding charset.Encoding // character encoding of the output
+}
+
+// ndjsonLine represents one line of NDJSON output.
+type ndjsonLine struct {
+	Section string `json:"section"`
+	ID      string `json:"id,omitempty"`
+	Value   any    `json:"value"`
+}
+
+func (e *NDJSON) setEncoding(enc charset.Encoding) {
+	e.Encoding = enc
+}
+
+func (e *NDJSON) Encode(w io.Writer, data map[string][]any) error {
+	enc := json.NewEncoder(charset.NewJSONWriter(w, e.Encoding))
+	return tree.WalkSections(data, func(section, id string, value any) error {
+		return enc.Encode(ndjsonLine{Section: section, ID: id, Value: value})
+	})
+}
+
+func (*NDJSON) Extension() string {
+	return ".ndjson"
+}
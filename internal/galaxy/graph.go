@@ -0,0 +1,365 @@
+package galaxy
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// Edge types other than the types of bypasses, e.g. "wormhole".
+const (
+	Hyperlane = "hyperlane"
+	Gateway   = "gateway"
+)
+
+// GraphFormats are the supported formats for exporting the galaxy as graph.
+var GraphFormats = []string{"graphml", "gexf", "dot"}
+
+// Edge represents an undirected connection between two systems.
+type Edge struct {
+	From   int    // ID of the system with the lower ID
+	To     int    // ID of the system with the higher ID
+	Type   string // "hyperlane" or the type of the bypass, e.g. "wormhole" or "gateway"
+	Length float64
+}
+
+// Edges returns all connections between the systems of a galaxy ordered by their systems and type.
+//
+// Connections are hyperlanes and active bypasses, which are linked to another bypass.
+// All active gateways are connected with each other.
+// Two systems can have several connections of different types, e.g. a hyperlane and a wormhole.
+func Edges(gs *stellaris.Gamestate) []Edge {
+	type key struct {
+		from, to int
+		typ      string
+	}
+	seen := make(map[key]bool)
+	var edges []Edge
+	add := func(a, b int, typ string, length float64) {
+		if a == b {
+			return
+		}
+		if _, ok := gs.Systems[a]; !ok {
+			return
+		}
+		if _, ok := gs.Systems[b]; !ok {
+			return
+		}
+		k := key{min(a, b), max(a, b), typ}
+		if seen[k] {
+			return
+		}
+		seen[k] = true
+		edges = append(edges, Edge{From: k.from, To: k.to, Type: typ, Length: length})
+	}
+	for _, id := range sortedKeys(gs.Systems) {
+		for _, h := range gs.Systems[id].Hyperlanes {
+			add(id, h.To, Hyperlane, h.Length)
+		}
+	}
+	var gateways []int
+	for _, id := range sortedKeys(gs.Bypasses) {
+		b := gs.Bypasses[id]
+		if !b.Active {
+			continue
+		}
+		if b.Type == Gateway {
+			gateways = append(gateways, b.SystemID)
+		}
+		if other, ok := gs.Bypasses[b.LinkedToID]; ok && other.Active {
+			add(b.SystemID, other.SystemID, b.Type, 0)
+		}
+	}
+	for i, a := range gateways {
+		for _, b := range gateways[i+1:] {
+			add(a, b, Gateway, 0)
+		}
+	}
+	slices.SortFunc(edges, func(a, b Edge) int {
+		if c := cmp.Compare(a.From, b.From); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.To, b.To); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Type, b.Type)
+	})
+	return edges
+}
+
+// node represents a system with it's attributes for exporting.
+type node struct {
+	id        int
+	name      string
+	ownerID   int
+	owner     string
+	starClass string
+	planets   int
+	x, y      float64
+}
+
+func nodes(gs *stellaris.Gamestate) []node {
+	var nn []node
+	for _, s := range sortedSystems(gs) {
+		n := node{
+			id:        s.ID,
+			name:      s.Name,
+			ownerID:   s.OwnerID,
+			starClass: s.StarClass,
+			planets:   len(s.PlanetIDs),
+			x:         s.X,
+			y:         s.Y,
+		}
+		if c, ok := gs.Countries[s.OwnerID]; ok {
+			n.owner = c.Name
+		}
+		nn = append(nn, n)
+	}
+	return nn
+}
+
+// attribute represents an attribute of nodes or edges.
+type attribute struct {
+	name, typ string
+}
+
+var nodeAttributes = []attribute{
+	{"name", "string"},
+	{"owner", "string"},
+	{"owner_id", "int"},
+	{"star_class", "string"},
+	{"planets", "int"},
+	{"x", "double"},
+	{"y", "double"},
+}
+
+var edgeAttributes = []attribute{
+	{"type", "string"},
+	{"length", "double"},
+}
+
+func (n node) values() []string {
+	return []string{
+		n.name,
+		n.owner,
+		strconv.Itoa(n.ownerID),
+		n.starClass,
+		strconv.Itoa(n.planets),
+		formatFloat(n.x),
+		formatFloat(n.y),
+	}
+}
+
+func (e Edge) values() []string {
+	return []string{e.Type, formatFloat(e.Length)}
+}
+
+// WriteGraph writes the galaxy as graph in the format to w.
+// Nodes are systems and edges are hyperlanes, wormholes and gateways.
+// Unowned systems have the owner ID -1.
+func WriteGraph(w io.Writer, gs *stellaris.Gamestate, format string) error {
+	switch format {
+	case "graphml":
+		return writeGraphML(w, gs)
+	case "gexf":
+		return writeGEXF(w, gs)
+	case "dot":
+		return writeDOT(w, gs)
+	}
+	return fmt.Errorf("invalid graph format: %s", format)
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+func writeGraphML(w io.Writer, gs *stellaris.Gamestate) error {
+	var g graphML
+	g.XMLNS = "http://graphml.graphdrawing.org/xmlns"
+	for _, a := range nodeAttributes {
+		g.Keys = append(g.Keys, graphMLKey{"node_" + a.name, "node", a.name, a.typ})
+	}
+	for _, a := range edgeAttributes {
+		g.Keys = append(g.Keys, graphMLKey{"edge_" + a.name, "edge", a.name, a.typ})
+	}
+	g.Graph.ID = "galaxy"
+	g.Graph.EdgeDefault = "undirected"
+	for _, n := range nodes(gs) {
+		x := graphMLNode{ID: strconv.Itoa(n.id)}
+		for i, v := range n.values() {
+			x.Data = append(x.Data, graphMLData{"node_" + nodeAttributes[i].name, v})
+		}
+		g.Graph.Nodes = append(g.Graph.Nodes, x)
+	}
+	for _, e := range Edges(gs) {
+		x := graphMLEdge{Source: strconv.Itoa(e.From), Target: strconv.Itoa(e.To)}
+		for i, v := range e.values() {
+			x.Data = append(x.Data, graphMLData{"edge_" + edgeAttributes[i].name, v})
+		}
+		g.Graph.Edges = append(g.Graph.Edges, x)
+	}
+	return writeXML(w, g)
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	ID     string      `xml:"id,attr"`
+	Label  string      `xml:"label,attr"`
+	Values []gexfValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID     string      `xml:"id,attr"`
+	Source string      `xml:"source,attr"`
+	Target string      `xml:"target,attr"`
+	Values []gexfValue `xml:"attvalues>attvalue"`
+}
+
+type gexf struct {
+	XMLName xml.Name `xml:"gexf"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Graph   struct {
+		DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+		Attributes      []gexfAttributes `xml:"attributes"`
+		Nodes           []gexfNode       `xml:"nodes>node"`
+		Edges           []gexfEdge       `xml:"edges>edge"`
+	} `xml:"graph"`
+}
+
+// gexfTypes maps the types of attributes to GEXF types.
+var gexfTypes = map[string]string{"string": "string", "int": "integer", "double": "double"}
+
+func writeGEXF(w io.Writer, gs *stellaris.Gamestate) error {
+	var g gexf
+	g.XMLNS = "http://gexf.net/1.3"
+	g.Version = "1.3"
+	g.Graph.DefaultEdgeType = "undirected"
+	for _, x := range []struct {
+		class      string
+		attributes []attribute
+	}{{"node", nodeAttributes}, {"edge", edgeAttributes}} {
+		aa := gexfAttributes{Class: x.class}
+		for _, a := range x.attributes {
+			aa.Attributes = append(aa.Attributes, gexfAttribute{a.name, a.name, gexfTypes[a.typ]})
+		}
+		g.Graph.Attributes = append(g.Graph.Attributes, aa)
+	}
+	for _, n := range nodes(gs) {
+		x := gexfNode{ID: strconv.Itoa(n.id), Label: n.name}
+		for i, v := range n.values() {
+			x.Values = append(x.Values, gexfValue{nodeAttributes[i].name, v})
+		}
+		g.Graph.Nodes = append(g.Graph.Nodes, x)
+	}
+	for i, e := range Edges(gs) {
+		x := gexfEdge{ID: strconv.Itoa(i), Source: strconv.Itoa(e.From), Target: strconv.Itoa(e.To)}
+		for i, v := range e.values() {
+			x.Values = append(x.Values, gexfValue{edgeAttributes[i].name, v})
+		}
+		g.Graph.Edges = append(g.Graph.Edges, x)
+	}
+	return writeXML(w, g)
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func writeDOT(w io.Writer, gs *stellaris.Gamestate) error {
+	var b strings.Builder
+	b.WriteString("graph galaxy {\n")
+	for _, n := range nodes(gs) {
+		fmt.Fprintf(&b, "  %d [label=%s", n.id, quoteDOT(n.name))
+		for i, v := range n.values() {
+			a := nodeAttributes[i]
+			if a.name == "name" {
+				continue // the name is already the label
+			}
+			if a.typ == "string" {
+				v = quoteDOT(v)
+			}
+			fmt.Fprintf(&b, ", %s=%s", a.name, v)
+		}
+		b.WriteString("];\n")
+	}
+	for _, e := range Edges(gs) {
+		fmt.Fprintf(&b, "  %d -- %d [type=%s, length=%s", e.From, e.To, quoteDOT(e.Type), formatFloat(e.Length))
+		if e.Type != Hyperlane {
+			b.WriteString(", style=dashed")
+		}
+		b.WriteString("];\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func quoteDOT(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func formatFloat(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
@@ -0,0 +1,103 @@
+package galaxy_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/galaxy"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+func TestEdges(t *testing.T) {
+	t.Run("should return hyperlanes and wormholes", func(t *testing.T) {
+		got := galaxy.Edges(stellaris.NewGamestate(testutil.LoadGamestate(t)))
+		assert.Equal(t, []galaxy.Edge{
+			{From: 0, To: 1, Type: "hyperlane", Length: 30},
+			{From: 0, To: 2, Type: "hyperlane", Length: 45},
+			{From: 1, To: 3, Type: "hyperlane", Length: 20},
+			{From: 2, To: 3, Type: "hyperlane", Length: 25},
+			{From: 3, To: 4, Type: "hyperlane", Length: 35},
+			{From: 4, To: 5, Type: "wormhole", Length: 0},
+		}, got)
+	})
+	t.Run("should connect active gateways", func(t *testing.T) {
+		gs := &stellaris.Gamestate{
+			Systems: map[int]*stellaris.System{
+				1: {ID: 1}, 2: {ID: 2}, 3: {ID: 3}, 4: {ID: 4},
+			},
+			Bypasses: map[int]*stellaris.Bypass{
+				0: {ID: 0, Type: "gateway", Active: true, SystemID: 1, LinkedToID: stellaris.NoID},
+				1: {ID: 1, Type: "gateway", Active: true, SystemID: 2, LinkedToID: stellaris.NoID},
+				2: {ID: 2, Type: "gateway", Active: true, SystemID: 3, LinkedToID: stellaris.NoID},
+				3: {ID: 3, Type: "gateway", Active: false, SystemID: 4, LinkedToID: stellaris.NoID},
+			},
+		}
+		got := galaxy.Edges(gs)
+		assert.Equal(t, []galaxy.Edge{
+			{From: 1, To: 2, Type: "gateway"},
+			{From: 1, To: 3, Type: "gateway"},
+			{From: 2, To: 3, Type: "gateway"},
+		}, got)
+	})
+	t.Run("should keep connections of different types between the same systems", func(t *testing.T) {
+		gs := &stellaris.Gamestate{
+			Systems: map[int]*stellaris.System{
+				1: {ID: 1, Hyperlanes: []stellaris.Hyperlane{{To: 2, Length: 10}}},
+				2: {ID: 2, Hyperlanes: []stellaris.Hyperlane{{To: 1, Length: 10}}},
+			},
+			Bypasses: map[int]*stellaris.Bypass{
+				0: {ID: 0, Type: "wormhole", Active: true, SystemID: 1, LinkedToID: 1},
+				1: {ID: 1, Type: "wormhole", Active: true, SystemID: 2, LinkedToID: 0},
+			},
+		}
+		got := galaxy.Edges(gs)
+		assert.Equal(t, []galaxy.Edge{
+			{From: 1, To: 2, Type: "hyperlane", Length: 10},
+			{From: 1, To: 2, Type: "wormhole"},
+		}, got)
+		assert.Equal(t, []int{2}, galaxy.NewGraph(gs).Neighbors(1))
+	})
+}
+
+func TestWriteGraph(t *testing.T) {
+	gs := stellaris.NewGamestate(testutil.LoadGamestate(t))
+	t.Run("should write GraphML", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, galaxy.WriteGraph(&buf, gs, "graphml"))
+		counts := countElements(t, buf.Bytes())
+		assert.Equal(t, 6, counts["node"])
+		assert.Equal(t, 6, counts["edge"])
+		assert.Equal(t, 9, counts["key"])
+		s := buf.String()
+		assert.Contains(t, s, `<data key="node_owner">Blooms of Gaea</data>`)
+		assert.Contains(t, s, `<data key="edge_type">wormhole</data>`)
+	})
+	t.Run("should write GEXF", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, galaxy.WriteGraph(&buf, gs, "gexf"))
+		counts := countElements(t, buf.Bytes())
+		assert.Equal(t, 6, counts["node"])
+		assert.Equal(t, 6, counts["edge"])
+		assert.Contains(t, buf.String(), `<node id="2" label="Kel">`)
+		assert.Contains(t, buf.String(), `<attvalue for="planets" value="2"></attvalue>`)
+	})
+	t.Run("should write DOT", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, galaxy.WriteGraph(&buf, gs, "dot"))
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 1+6+6+1)
+		assert.Equal(t, "graph galaxy {", lines[0])
+		assert.Equal(t, `  0 [label="Gaea", owner="Blooms of Gaea", owner_id=0, star_class="sc_g", planets=2, x=0, y=0];`, lines[1])
+		assert.Equal(t, `  4 -- 5 [type="wormhole", length=0, style=dashed];`, lines[12])
+		assert.Equal(t, "}", lines[13])
+	})
+	t.Run("should return error for invalid format", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Error(t, galaxy.WriteGraph(&buf, gs, "xls"))
+	})
+}
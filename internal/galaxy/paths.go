@@ -0,0 +1,162 @@
+package galaxy
+
+import (
+	"slices"
+
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// Graph represents the galaxy as undirected graph with systems as nodes
+// and hyperlanes, wormholes and gateways as edges.
+// Each edge is one jump.
+type Graph struct {
+	systems   []int         // IDs of all systems in ascending order
+	neighbors map[int][]int // IDs of neighbors in ascending order by system
+}
+
+// NewGraph returns the graph of the galaxy of a gamestate.
+func NewGraph(gs *stellaris.Gamestate) *Graph {
+	g := &Graph{systems: sortedKeys(gs.Systems), neighbors: make(map[int][]int)}
+	for _, e := range Edges(gs) {
+		g.neighbors[e.From] = append(g.neighbors[e.From], e.To)
+		g.neighbors[e.To] = append(g.neighbors[e.To], e.From)
+	}
+	for id, nn := range g.neighbors {
+		slices.Sort(nn)
+		g.neighbors[id] = slices.Compact(nn) // systems can have several connections
+	}
+	return g
+}
+
+// Neighbors returns the IDs of the systems, which are connected to a system, in ascending order.
+func (g *Graph) Neighbors(id int) []int {
+	return g.neighbors[id]
+}
+
+// ShortestPath returns the systems on a path with the least jumps between two systems
+// including both systems and reports whether a path exists.
+// When there are several shortest paths, the path through the systems with the lowest IDs is returned.
+func (g *Graph) ShortestPath(from, to int) ([]int, bool) {
+	if !slices.Contains(g.systems, from) || !slices.Contains(g.systems, to) {
+		return nil, false
+	}
+	previous := map[int]int{from: from}
+	queue := []int{from}
+	for len(queue) > 0 && !hasKey(previous, to) {
+		id := queue[0]
+		queue = queue[1:]
+		for _, n := range g.neighbors[id] {
+			if !hasKey(previous, n) {
+				previous[n] = id
+				queue = append(queue, n)
+			}
+		}
+	}
+	if !hasKey(previous, to) {
+		return nil, false
+	}
+	path := []int{to}
+	for id := to; id != from; {
+		id = previous[id]
+		path = append(path, id)
+	}
+	slices.Reverse(path)
+	return path, true
+}
+
+// Jumps returns the least number of jumps from a system to all systems, which can be reached from it.
+func (g *Graph) Jumps(from int) map[int]int {
+	jumps := make(map[int]int)
+	if !slices.Contains(g.systems, from) {
+		return jumps
+	}
+	jumps[from] = 0
+	queue := []int{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, n := range g.neighbors[id] {
+			if !hasKey(jumps, n) {
+				jumps[n] = jumps[id] + 1
+				queue = append(queue, n)
+			}
+		}
+	}
+	return jumps
+}
+
+// Chokepoints returns the IDs of all systems in ascending order,
+// which disconnect parts of the galaxy from each other when they are removed.
+// These are the articulation points of the graph.
+func (g *Graph) Chokepoints() []int {
+	order := make(map[int]int) // order in which systems are visited
+	low := make(map[int]int)   // lowest order of systems reachable through the subtree of a system
+	found := make(map[int]bool)
+	var visit func(id, parent int)
+	visit = func(id, parent int) {
+		order[id] = len(order)
+		low[id] = order[id]
+		var children int
+		for _, n := range g.neighbors[id] {
+			if n == parent {
+				continue
+			}
+			if o, ok := order[n]; ok {
+				low[id] = min(low[id], o)
+				continue
+			}
+			children++
+			visit(n, id)
+			low[id] = min(low[id], low[n])
+			if parent != stellaris.NoID && low[n] >= order[id] {
+				found[id] = true
+			}
+		}
+		if parent == stellaris.NoID && children > 1 {
+			found[id] = true
+		}
+	}
+	for _, id := range g.systems {
+		if !hasKey(order, id) {
+			visit(id, stellaris.NoID)
+		}
+	}
+	return sortedKeys(found)
+}
+
+// BorderSystems returns the IDs of all systems of a country in ascending order,
+// which are connected to systems not owned by the country.
+func BorderSystems(gs *stellaris.Gamestate, g *Graph, countryID int) []int {
+	var r []int
+	for _, id := range g.systems {
+		if gs.Systems[id].OwnerID != countryID {
+			continue
+		}
+		if slices.ContainsFunc(g.neighbors[id], func(n int) bool { return gs.Systems[n].OwnerID != countryID }) {
+			r = append(r, id)
+		}
+	}
+	return r
+}
+
+// CapitalSystem returns the ID of the system with the capital of a country
+// and reports whether it was found.
+func CapitalSystem(gs *stellaris.Gamestate, countryID int) (int, bool) {
+	c, ok := gs.Countries[countryID]
+	if !ok {
+		return stellaris.NoID, false
+	}
+	p, ok := gs.Planets[c.CapitalID]
+	if !ok {
+		return stellaris.NoID, false
+	}
+	if _, ok := gs.Systems[p.SystemID]; !ok {
+		return stellaris.NoID, false
+	}
+	return p.SystemID, true
+}
+
+func hasKey[K comparable, V any](m map[K]V, k K) bool {
+	_, ok := m[k]
+	return ok
+}
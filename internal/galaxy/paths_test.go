@@ -0,0 +1,62 @@
+package galaxy_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/galaxy"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+func TestGraph(t *testing.T) {
+	gs := stellaris.NewGamestate(testutil.LoadGamestate(t))
+	g := galaxy.NewGraph(gs)
+	t.Run("should return neighbors", func(t *testing.T) {
+		assert.Equal(t, []int{1, 2}, g.Neighbors(0))
+		assert.Equal(t, []int{3, 5}, g.Neighbors(4))
+	})
+	t.Run("should find shortest path", func(t *testing.T) {
+		got, ok := g.ShortestPath(0, 5)
+		assert.True(t, ok)
+		assert.Equal(t, []int{0, 1, 3, 4, 5}, got)
+		got, ok = g.ShortestPath(2, 2)
+		assert.True(t, ok)
+		assert.Equal(t, []int{2}, got)
+	})
+	t.Run("should report when there is no path", func(t *testing.T) {
+		gs := &stellaris.Gamestate{Systems: map[int]*stellaris.System{1: {ID: 1}, 2: {ID: 2}}}
+		_, ok := galaxy.NewGraph(gs).ShortestPath(1, 2)
+		assert.False(t, ok)
+		_, ok = g.ShortestPath(0, 99)
+		assert.False(t, ok)
+	})
+	t.Run("should return jumps", func(t *testing.T) {
+		assert.Equal(t, map[int]int{0: 0, 1: 1, 2: 1, 3: 2, 4: 3, 5: 4}, g.Jumps(0))
+		assert.Empty(t, g.Jumps(99))
+	})
+	t.Run("should find chokepoints", func(t *testing.T) {
+		assert.Equal(t, []int{3, 4}, g.Chokepoints())
+	})
+	t.Run("should find chokepoint at start of search", func(t *testing.T) {
+		gs := &stellaris.Gamestate{Systems: map[int]*stellaris.System{
+			0: {ID: 0, Hyperlanes: []stellaris.Hyperlane{{To: 1}, {To: 2}}},
+			1: {ID: 1},
+			2: {ID: 2},
+		}}
+		assert.Equal(t, []int{0}, galaxy.NewGraph(gs).Chokepoints())
+	})
+	t.Run("should find border systems", func(t *testing.T) {
+		assert.Equal(t, []int{0}, galaxy.BorderSystems(gs, g, 0))
+		assert.Equal(t, []int{2}, galaxy.BorderSystems(gs, g, 2))
+		assert.Empty(t, galaxy.BorderSystems(gs, g, 99))
+	})
+	t.Run("should find capital system", func(t *testing.T) {
+		id, ok := galaxy.CapitalSystem(gs, 1)
+		assert.True(t, ok)
+		assert.Equal(t, 1, id)
+		_, ok = galaxy.CapitalSystem(gs, 99)
+		assert.False(t, ok)
+	})
+}
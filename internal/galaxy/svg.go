@@ -0,0 +1,221 @@
+// Package galaxy provides tools for the galaxy of a save game,
+// which consists of star systems connected by hyperlanes.
+package galaxy
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// DefaultWidth is the default width of SVG maps in pixels.
+const DefaultWidth = 1000
+
+// SVGOptions represents the options for rendering a galaxy map.
+type SVGOptions struct {
+	Width  int  // width of the map in pixels. Defaults to [DefaultWidth].
+	Labels bool // show the names of systems
+}
+
+// flagColors maps the names of flag colors to colors for maps.
+var flagColors = map[string]string{
+	"beige":        "#c8b98c",
+	"black":        "#303030",
+	"blue":         "#2e5cb8",
+	"brown":        "#8a5a2b",
+	"burgundy":     "#800030",
+	"dark_blue":    "#1c2f80",
+	"dark_brown":   "#5a3a1a",
+	"dark_green":   "#1f5f2a",
+	"dark_grey":    "#555555",
+	"dark_purple":  "#4b1f6f",
+	"dark_red":     "#8b1a1a",
+	"dark_teal":    "#1f5f5f",
+	"frog_green":   "#6fa83a",
+	"green":        "#2e8b3a",
+	"grey":         "#8c8c8c",
+	"indigo":       "#3f2f9f",
+	"light_blue":   "#6fa8dc",
+	"light_green":  "#8fd18f",
+	"light_orange": "#f5b36b",
+	"orange":       "#e0781f",
+	"pink":         "#e07fb0",
+	"purple":       "#7f3fbf",
+	"red":          "#c0282d",
+	"red_orange":   "#d9531e",
+	"shadow_teal":  "#2f6f6f",
+	"sky_blue":     "#5fbfef",
+	"teal":         "#2f9f9f",
+	"toxic_green":  "#7fdf1f",
+	"turquoise":    "#3fcfbf",
+	"white":        "#e8e8e8",
+	"yellow":       "#e6c629",
+}
+
+// countryColor returns the color of a country on maps,
+// which is the first known color of it's flag.
+// Countries without a known flag color get a color derived from their ID.
+func countryColor(c *stellaris.Country) string {
+	for _, name := range c.FlagColors {
+		if color, ok := flagColors[name]; ok {
+			return color
+		}
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d", c.ID)
+	return fmt.Sprintf("hsl(%d, 60%%, 50%%)", h.Sum32()%360)
+}
+
+// bounds represents the area of the galaxy, which is rendered on a map.
+type bounds struct {
+	minX, minY, maxX, maxY float64
+	scale, margin          float64
+}
+
+func (b bounds) x(x float64) float64 {
+	return (x-b.minX)*b.scale + b.margin
+}
+
+func (b bounds) y(y float64) float64 {
+	return (y-b.minY)*b.scale + b.margin
+}
+
+// WriteSVG renders the galaxy of a gamestate as SVG map to w.
+//
+// Systems are drawn as points and hyperlanes as lines between them.
+// The territory of countries is drawn around the systems they own with the color of their flag.
+// The legend with the names of the countries is drawn below the map.
+func WriteSVG(w io.Writer, gs *stellaris.Gamestate, opt SVGOptions) error {
+	width := opt.Width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	systems := sortedSystems(gs)
+	if len(systems) == 0 {
+		return fmt.Errorf("galaxy has no systems")
+	}
+	b := bounds{minX: systems[0].X, maxX: systems[0].X, minY: systems[0].Y, maxY: systems[0].Y}
+	for _, s := range systems {
+		b.minX, b.maxX = min(b.minX, s.X), max(b.maxX, s.X)
+		b.minY, b.maxY = min(b.minY, s.Y), max(b.maxY, s.Y)
+	}
+	b.margin = float64(width) * 0.05
+	b.scale = (float64(width) - 2*b.margin) / max(b.maxX-b.minX, b.maxY-b.minY, 1)
+	radius := territoryRadius(systems) * b.scale
+	owners := make(map[int][]*stellaris.System)
+	for _, s := range systems {
+		if _, ok := gs.Countries[s.OwnerID]; ok {
+			owners[s.OwnerID] = append(owners[s.OwnerID], s)
+		}
+	}
+	ownerIDs := sortedKeys(owners)
+	// The legend is drawn below the map, so it never covers any systems or territories.
+	legendY := int(b.y(b.maxY) + max(b.margin, radius))
+	height := legendY + len(ownerIDs)*18 + 10
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="#0b0f1a"/>`+"\n")
+
+	// territory
+	fmt.Fprintln(bw, `<g id="territory" opacity="0.35">`)
+	for _, id := range ownerIDs {
+		fmt.Fprintf(bw, `<g fill="%s">`+"\n", countryColor(gs.Countries[id]))
+		for _, s := range owners[id] {
+			fmt.Fprintf(bw, `<circle cx="%.1f" cy="%.1f" r="%.1f"/>`+"\n", b.x(s.X), b.y(s.Y), radius)
+		}
+		fmt.Fprintln(bw, `</g>`)
+	}
+	fmt.Fprintln(bw, `</g>`)
+
+	// hyperlanes
+	fmt.Fprintln(bw, `<g id="hyperlanes" stroke="#5a6b8c" stroke-width="1">`)
+	for _, s := range systems {
+		for _, h := range s.Hyperlanes {
+			other, ok := gs.Systems[h.To]
+			if !ok || (h.To < s.ID && slices.ContainsFunc(other.Hyperlanes, func(x stellaris.Hyperlane) bool { return x.To == s.ID })) {
+				continue // draw each hyperlane only once
+			}
+			fmt.Fprintf(bw, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f"/>`+"\n", b.x(s.X), b.y(s.Y), b.x(other.X), b.y(other.Y))
+		}
+	}
+	fmt.Fprintln(bw, `</g>`)
+
+	// systems
+	fmt.Fprintln(bw, `<g id="systems">`)
+	for _, s := range systems {
+		fill := "#cccccc"
+		title := s.Name
+		if c, ok := gs.Countries[s.OwnerID]; ok {
+			fill = countryColor(c)
+			title += " (" + c.Name + ")"
+		}
+		fmt.Fprintf(bw, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s</title></circle>`+"\n", b.x(s.X), b.y(s.Y), fill, escape(title))
+	}
+	fmt.Fprintln(bw, `</g>`)
+
+	if opt.Labels {
+		fmt.Fprintln(bw, `<g id="labels" fill="#ffffff" font-family="sans-serif" font-size="10">`)
+		for _, s := range systems {
+			fmt.Fprintf(bw, `<text x="%.1f" y="%.1f">%s</text>`+"\n", b.x(s.X)+5, b.y(s.Y)-5, escape(s.Name))
+		}
+		fmt.Fprintln(bw, `</g>`)
+	}
+
+	// legend
+	fmt.Fprintln(bw, `<g id="legend" font-family="sans-serif" font-size="12">`)
+	for i, id := range ownerIDs {
+		c := gs.Countries[id]
+		y := legendY + 10 + i*18
+		fmt.Fprintf(bw, `<rect x="%.1f" y="%d" width="12" height="12" fill="%s"/>`+"\n", b.margin, y-10, countryColor(c))
+		fmt.Fprintf(bw, `<text x="%.1f" y="%d" fill="#ffffff">%s</text>`+"\n", b.margin+18, y, escape(c.Name))
+	}
+	fmt.Fprintln(bw, `</g>`)
+	fmt.Fprintln(bw, `</svg>`)
+	return bw.Flush()
+}
+
+// territoryRadius returns the radius of the territory around a system in galaxy units,
+// which is derived from the median length of the hyperlanes.
+func territoryRadius(systems []*stellaris.System) float64 {
+	var lengths []float64
+	for _, s := range systems {
+		for _, h := range s.Hyperlanes {
+			lengths = append(lengths, h.Length)
+		}
+	}
+	if len(lengths) == 0 {
+		return 20
+	}
+	slices.Sort(lengths)
+	return lengths[len(lengths)/2] * 0.6
+}
+
+func sortedSystems(gs *stellaris.Gamestate) []*stellaris.System {
+	systems := make([]*stellaris.System, 0, len(gs.Systems))
+	for _, id := range sortedKeys(gs.Systems) {
+		systems = append(systems, gs.Systems[id])
+	}
+	return systems
+}
+
+func sortedKeys[T any](m map[int]T) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
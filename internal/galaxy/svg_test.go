@@ -0,0 +1,87 @@
+package galaxy_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/galaxy"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+func TestWriteSVG(t *testing.T) {
+	gs := stellaris.NewGamestate(testutil.LoadGamestate(t))
+	t.Run("should render galaxy", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, galaxy.WriteSVG(&buf, gs, galaxy.SVGOptions{}))
+		counts := countElements(t, buf.Bytes())
+		assert.Equal(t, 5, counts["line"], "hyperlanes")
+		assert.Equal(t, 3+6, counts["circle"], "territories and systems")
+		assert.Equal(t, 3, counts["text"], "legend")
+		s := buf.String()
+		assert.Contains(t, s, `width="1000"`)
+		assert.Contains(t, s, `<g fill="#7fdf1f">`, "color of the first country")
+		assert.Contains(t, s, "<title>Gaea (Blooms of Gaea)</title>")
+		assert.Contains(t, s, "<title>Rock</title>")
+	})
+	t.Run("should render labels", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, galaxy.WriteSVG(&buf, gs, galaxy.SVGOptions{Width: 500, Labels: true}))
+		counts := countElements(t, buf.Bytes())
+		assert.Equal(t, 3+6, counts["text"])
+		assert.Contains(t, buf.String(), `width="500"`)
+	})
+	t.Run("should draw legend below the map", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, galaxy.WriteSVG(&buf, gs, galaxy.SVGOptions{}))
+		var doc struct {
+			Height  float64 `xml:"height,attr"`
+			Circles []struct {
+				CY float64 `xml:"cy,attr"`
+				R  float64 `xml:"r,attr"`
+			} `xml:"g>g>circle"`
+			Legend []struct {
+				Y float64 `xml:"y,attr"`
+			} `xml:"g>rect"`
+		}
+		require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+		require.NotEmpty(t, doc.Circles)
+		require.Len(t, doc.Legend, 3)
+		var bottom float64
+		for _, c := range doc.Circles {
+			bottom = max(bottom, c.CY+c.R)
+		}
+		for _, r := range doc.Legend {
+			assert.GreaterOrEqual(t, r.Y, bottom)
+			assert.LessOrEqual(t, r.Y+12, doc.Height)
+		}
+	})
+	t.Run("should return error when galaxy has no systems", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := galaxy.WriteSVG(&buf, &stellaris.Gamestate{}, galaxy.SVGOptions{})
+		assert.Error(t, err)
+	})
+}
+
+// countElements returns the number of elements by name in a XML document and fails when it is invalid.
+func countElements(t *testing.T, data []byte) map[string]int {
+	counts := make(map[string]int)
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if se, ok := tok.(xml.StartElement); ok {
+			counts[strings.ToLower(se.Name.Local)]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,426 @@
+// Package graphql provides a GraphQL API for the typed model of a save game.
+//
+// The schema has a query field for each collection, e.g. countries, and for single entities, e.g. country(id: 0).
+// References between entities are resolved into fields,
+// e.g. countries → planets → pops → species or fleets → ships.
+// References which are not set or point to missing entities are null.
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	gographql "github.com/graphql-go/graphql"
+
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// request represents a GraphQL request.
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Handler is a HTTP handler for GraphQL requests.
+// It accepts queries with GET as parameter "query" and with POST as JSON body.
+type Handler struct {
+	schema gographql.Schema
+}
+
+// NewHandler returns a new handler for the gamestate.
+func NewHandler(gs *stellaris.Gamestate) (*Handler, error) {
+	schema, err := NewSchema(gs)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{schema: schema}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, "invalid variables", http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	result := Do(h.schema, req.Query, req.OperationName, req.Variables)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Do executes a query against the schema.
+func Do(schema gographql.Schema, query, operationName string, variables map[string]any) *gographql.Result {
+	return gographql.Do(gographql.Params{
+		Schema:         schema,
+		RequestString:  query,
+		OperationName:  operationName,
+		VariableValues: variables,
+	})
+}
+
+// relations contains the reverse references between entities.
+type relations struct {
+	planetsByOwner map[int][]*stellaris.Planet
+	fleetsByOwner  map[int][]*stellaris.Fleet
+	leadersByOwner map[int][]*stellaris.Leader
+	popsBySpecies  map[int][]*stellaris.Pop
+	warsByCountry  map[int][]*stellaris.War
+}
+
+func newRelations(gs *stellaris.Gamestate) *relations {
+	r := &relations{
+		planetsByOwner: make(map[int][]*stellaris.Planet),
+		fleetsByOwner:  make(map[int][]*stellaris.Fleet),
+		leadersByOwner: make(map[int][]*stellaris.Leader),
+		popsBySpecies:  make(map[int][]*stellaris.Pop),
+		warsByCountry:  make(map[int][]*stellaris.War),
+	}
+	for _, p := range sorted(gs.Planets) {
+		r.planetsByOwner[p.OwnerID] = append(r.planetsByOwner[p.OwnerID], p)
+	}
+	for _, f := range sorted(gs.Fleets) {
+		r.fleetsByOwner[f.OwnerID] = append(r.fleetsByOwner[f.OwnerID], f)
+	}
+	for _, l := range sorted(gs.Leaders) {
+		r.leadersByOwner[l.CountryID] = append(r.leadersByOwner[l.CountryID], l)
+	}
+	for _, p := range sorted(gs.Pops) {
+		r.popsBySpecies[p.SpeciesID] = append(r.popsBySpecies[p.SpeciesID], p)
+	}
+	for _, w := range sorted(gs.Wars) {
+		for _, p := range slices.Concat(w.Attackers, w.Defenders) {
+			r.warsByCountry[p.CountryID] = append(r.warsByCountry[p.CountryID], w)
+		}
+	}
+	return r
+}
+
+// sorted returns the entities of a collection ordered by ID.
+func sorted[T any](m map[int]*T) []*T {
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	r := make([]*T, 0, len(ids))
+	for _, id := range ids {
+		r = append(r, m[id])
+	}
+	return r
+}
+
+// orEmpty returns s or an empty slice when s is nil,
+// since lists in the schema are not nullable.
+func orEmpty[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// lookup returns the entities for the IDs. Missing entities are skipped.
+func lookup[T any](m map[int]*T, ids []int) []*T {
+	r := make([]*T, 0, len(ids))
+	for _, id := range ids {
+		if x, ok := m[id]; ok {
+			r = append(r, x)
+		}
+	}
+	return r
+}
+
+// ref returns a resolver for a reference to an entity in m.
+// The function id returns the ID of the reference from the source entity.
+func ref[S, T any](m map[int]*T, id func(*S) int) gographql.FieldResolveFn {
+	return func(p gographql.ResolveParams) (any, error) {
+		x, ok := m[id(p.Source.(*S))]
+		if !ok {
+			return nil, nil
+		}
+		return x, nil
+	}
+}
+
+// refs returns a resolver for references to entities in m.
+func refs[S, T any](m map[int]*T, ids func(*S) []int) gographql.FieldResolveFn {
+	return func(p gographql.ResolveParams) (any, error) {
+		return lookup(m, ids(p.Source.(*S))), nil
+	}
+}
+
+// collection returns the query fields for a collection and for single entities of it.
+func collection[T any](t *gographql.Object, m map[int]*T) (*gographql.Field, *gographql.Field) {
+	list := &gographql.Field{
+		Type: gographql.NewNonNull(gographql.NewList(gographql.NewNonNull(t))),
+		Args: gographql.FieldConfigArgument{
+			"ids": &gographql.ArgumentConfig{Type: gographql.NewList(gographql.NewNonNull(gographql.Int))},
+		},
+		Resolve: func(p gographql.ResolveParams) (any, error) {
+			ids, ok := p.Args["ids"].([]any)
+			if !ok {
+				return sorted(m), nil
+			}
+			r := make([]*T, 0, len(ids))
+			for _, id := range ids {
+				if x, ok := m[id.(int)]; ok {
+					r = append(r, x)
+				}
+			}
+			return r, nil
+		},
+	}
+	single := &gographql.Field{
+		Type: t,
+		Args: gographql.FieldConfigArgument{
+			"id": &gographql.ArgumentConfig{Type: gographql.NewNonNull(gographql.Int)},
+		},
+		Resolve: func(p gographql.ResolveParams) (any, error) {
+			x, ok := m[p.Args["id"].(int)]
+			if !ok {
+				return nil, nil
+			}
+			return x, nil
+		},
+	}
+	return list, single
+}
+
+// field returns a field with a scalar value from the source entity.
+func field[S any](t gographql.Output, value func(*S) any) *gographql.Field {
+	return &gographql.Field{
+		Type: t,
+		Resolve: func(p gographql.ResolveParams) (any, error) {
+			return value(p.Source.(*S)), nil
+		},
+	}
+}
+
+// id returns an ID as value for a field, which is null when the ID is not set.
+func id(x int) any {
+	if x == stellaris.NoID {
+		return nil
+	}
+	return x
+}
+
+// NewSchema returns the GraphQL schema for the gamestate.
+func NewSchema(gs *stellaris.Gamestate) (gographql.Schema, error) {
+	rel := newRelations(gs)
+	var (
+		countryType, speciesType, planetType, popType, leaderType     *gographql.Object
+		fleetType, shipType, warType, participantType, federationType *gographql.Object
+	)
+	list := func(t gographql.Type) gographql.Output {
+		return gographql.NewNonNull(gographql.NewList(gographql.NewNonNull(t)))
+	}
+	str := gographql.NewNonNull(gographql.String)
+	integer := gographql.NewNonNull(gographql.Int)
+	float := gographql.NewNonNull(gographql.Float)
+	boolean := gographql.NewNonNull(gographql.Boolean)
+
+	countryType = gographql.NewObject(gographql.ObjectConfig{
+		Name:        "Country",
+		Description: "An empire or another kind of country",
+		Fields: gographql.FieldsThunk(func() gographql.Fields {
+			return gographql.Fields{
+				"id":             field(integer, func(c *stellaris.Country) any { return c.ID }),
+				"name":           field(str, func(c *stellaris.Country) any { return c.Name }),
+				"adjective":      field(str, func(c *stellaris.Country) any { return c.Adjective }),
+				"type":           field(str, func(c *stellaris.Country) any { return c.Type }),
+				"personality":    field(str, func(c *stellaris.Country) any { return c.Personality }),
+				"militaryPower":  field(float, func(c *stellaris.Country) any { return c.MilitaryPower }),
+				"economyPower":   field(float, func(c *stellaris.Country) any { return c.EconomyPower }),
+				"techPower":      field(float, func(c *stellaris.Country) any { return c.TechPower }),
+				"victoryRank":    field(integer, func(c *stellaris.Country) any { return c.VictoryRank }),
+				"victoryScore":   field(float, func(c *stellaris.Country) any { return c.VictoryScore }),
+				"fleetSize":      field(integer, func(c *stellaris.Country) any { return c.FleetSize }),
+				"empireSize":     field(integer, func(c *stellaris.Country) any { return c.EmpireSize }),
+				"capital":        {Type: planetType, Resolve: ref(gs.Planets, func(c *stellaris.Country) int { return c.CapitalID })},
+				"founderSpecies": {Type: speciesType, Resolve: ref(gs.Species, func(c *stellaris.Country) int { return c.FounderSpeciesID })},
+				"ruler":          {Type: leaderType, Resolve: ref(gs.Leaders, func(c *stellaris.Country) int { return c.RulerID })},
+				"federation":     {Type: federationType, Resolve: ref(gs.Federations, func(c *stellaris.Country) int { return c.FederationID })},
+				"planets":        field(list(planetType), func(c *stellaris.Country) any { return orEmpty(rel.planetsByOwner[c.ID]) }),
+				"fleets":         field(list(fleetType), func(c *stellaris.Country) any { return orEmpty(rel.fleetsByOwner[c.ID]) }),
+				"leaders":        field(list(leaderType), func(c *stellaris.Country) any { return orEmpty(rel.leadersByOwner[c.ID]) }),
+				"wars":           field(list(warType), func(c *stellaris.Country) any { return orEmpty(rel.warsByCountry[c.ID]) }),
+			}
+		}),
+	})
+	speciesType = gographql.NewObject(gographql.ObjectConfig{
+		Name: "Species",
+		Fields: gographql.FieldsThunk(func() gographql.Fields {
+			return gographql.Fields{
+				"id":         field(integer, func(s *stellaris.Species) any { return s.ID }),
+				"name":       field(str, func(s *stellaris.Species) any { return s.Name }),
+				"plural":     field(str, func(s *stellaris.Species) any { return s.Plural }),
+				"adjective":  field(str, func(s *stellaris.Species) any { return s.Adjective }),
+				"class":      field(str, func(s *stellaris.Species) any { return s.Class }),
+				"portrait":   field(str, func(s *stellaris.Species) any { return s.Portrait }),
+				"traits":     field(list(gographql.String), func(s *stellaris.Species) any { return orEmpty(s.Traits) }),
+				"homePlanet": {Type: planetType, Resolve: ref(gs.Planets, func(s *stellaris.Species) int { return s.HomePlanetID })},
+				"pops":       field(list(popType), func(s *stellaris.Species) any { return orEmpty(rel.popsBySpecies[s.ID]) }),
+			}
+		}),
+	})
+	planetType = gographql.NewObject(gographql.ObjectConfig{
+		Name: "Planet",
+		Fields: gographql.FieldsThunk(func() gographql.Fields {
+			return gographql.Fields{
+				"id":            field(integer, func(p *stellaris.Planet) any { return p.ID }),
+				"name":          field(str, func(p *stellaris.Planet) any { return p.Name }),
+				"class":         field(str, func(p *stellaris.Planet) any { return p.Class }),
+				"size":          field(integer, func(p *stellaris.Planet) any { return p.Size }),
+				"systemId":      field(gographql.Int, func(p *stellaris.Planet) any { return id(p.SystemID) }),
+				"stability":     field(float, func(p *stellaris.Planet) any { return p.Stability }),
+				"owner":         {Type: countryType, Resolve: ref(gs.Countries, func(p *stellaris.Planet) int { return p.OwnerID })},
+				"controller":    {Type: countryType, Resolve: ref(gs.Countries, func(p *stellaris.Planet) int { return p.ControllerID })},
+				"originalOwner": {Type: countryType, Resolve: ref(gs.Countries, func(p *stellaris.Planet) int { return p.OriginalOwnerID })},
+				"pops":          {Type: list(popType), Resolve: refs(gs.Pops, func(p *stellaris.Planet) []int { return p.PopIDs })},
+			}
+		}),
+	})
+	popType = gographql.NewObject(gographql.ObjectConfig{
+		Name: "Pop",
+		Fields: gographql.FieldsThunk(func() gographql.Fields {
+			return gographql.Fields{
+				"id":       field(integer, func(p *stellaris.Pop) any { return p.ID }),
+				"job":      field(str, func(p *stellaris.Pop) any { return p.Job }),
+				"category": field(str, func(p *stellaris.Pop) any { return p.Category }),
+				"species":  {Type: speciesType, Resolve: ref(gs.Species, func(p *stellaris.Pop) int { return p.SpeciesID })},
+				"planet":   {Type: planetType, Resolve: ref(gs.Planets, func(p *stellaris.Pop) int { return p.PlanetID })},
+			}
+		}),
+	})
+	leaderType = gographql.NewObject(gographql.ObjectConfig{
+		Name: "Leader",
+		Fields: gographql.FieldsThunk(func() gographql.Fields {
+			return gographql.Fields{
+				"id":      field(integer, func(l *stellaris.Leader) any { return l.ID }),
+				"name":    field(str, func(l *stellaris.Leader) any { return l.Name }),
+				"class":   field(str, func(l *stellaris.Leader) any { return l.Class }),
+				"level":   field(integer, func(l *stellaris.Leader) any { return l.Level }),
+				"age":     field(integer, func(l *stellaris.Leader) any { return l.Age }),
+				"species": {Type: speciesType, Resolve: ref(gs.Species, func(l *stellaris.Leader) int { return l.SpeciesID })},
+				"country": {Type: countryType, Resolve: ref(gs.Countries, func(l *stellaris.Leader) int { return l.CountryID })},
+			}
+		}),
+	})
+	fleetType = gographql.NewObject(gographql.ObjectConfig{
+		Name: "Fleet",
+		Fields: gographql.FieldsThunk(func() gographql.Fields {
+			return gographql.Fields{
+				"id":            field(integer, func(f *stellaris.Fleet) any { return f.ID }),
+				"name":          field(str, func(f *stellaris.Fleet) any { return f.Name }),
+				"systemId":      field(gographql.Int, func(f *stellaris.Fleet) any { return id(f.SystemID) }),
+				"militaryPower": field(float, func(f *stellaris.Fleet) any { return f.MilitaryPower }),
+				"isStation":     field(boolean, func(f *stellaris.Fleet) any { return f.IsStation }),
+				"isCivilian":    field(boolean, func(f *stellaris.Fleet) any { return f.IsCivilian }),
+				"owner":         {Type: countryType, Resolve: ref(gs.Countries, func(f *stellaris.Fleet) int { return f.OwnerID })},
+				"ships":         {Type: list(shipType), Resolve: refs(gs.Ships, func(f *stellaris.Fleet) []int { return f.ShipIDs })},
+			}
+		}),
+	})
+	shipType = gographql.NewObject(gographql.ObjectConfig{
+		Name: "Ship",
+		Fields: gographql.FieldsThunk(func() gographql.Fields {
+			return gographql.Fields{
+				"id":        field(integer, func(s *stellaris.Ship) any { return s.ID }),
+				"name":      field(str, func(s *stellaris.Ship) any { return s.Name }),
+				"designId":  field(gographql.Int, func(s *stellaris.Ship) any { return id(s.DesignID) }),
+				"hitpoints": field(float, func(s *stellaris.Ship) any { return s.Hitpoints }),
+				"fleet":     {Type: fleetType, Resolve: ref(gs.Fleets, func(s *stellaris.Ship) int { return s.FleetID })},
+				"leader":    {Type: leaderType, Resolve: ref(gs.Leaders, func(s *stellaris.Ship) int { return s.LeaderID })},
+			}
+		}),
+	})
+	participantType = gographql.NewObject(gographql.ObjectConfig{
+		Name: "WarParticipant",
+		Fields: gographql.FieldsThunk(func() gographql.Fields {
+			return gographql.Fields{
+				"callType": field(str, func(p *stellaris.WarParticipant) any { return p.CallType }),
+				"country":  {Type: countryType, Resolve: ref(gs.Countries, func(p *stellaris.WarParticipant) int { return p.CountryID })},
+			}
+		}),
+	})
+	participants := func(pp []stellaris.WarParticipant) []*stellaris.WarParticipant {
+		r := make([]*stellaris.WarParticipant, len(pp))
+		for i := range pp {
+			r[i] = &pp[i]
+		}
+		return r
+	}
+	warType = gographql.NewObject(gographql.ObjectConfig{
+		Name: "War",
+		Fields: gographql.FieldsThunk(func() gographql.Fields {
+			return gographql.Fields{
+				"id":                    field(integer, func(w *stellaris.War) any { return w.ID }),
+				"name":                  field(str, func(w *stellaris.War) any { return w.Name }),
+				"startDate":             field(str, func(w *stellaris.War) any { return w.StartDate }),
+				"attackerWarGoal":       field(str, func(w *stellaris.War) any { return w.AttackerWarGoal }),
+				"defenderWarGoal":       field(str, func(w *stellaris.War) any { return w.DefenderWarGoal }),
+				"attackerWarExhaustion": field(float, func(w *stellaris.War) any { return w.AttackerWarExhaustion }),
+				"defenderWarExhaustion": field(float, func(w *stellaris.War) any { return w.DefenderWarExhaustion }),
+				"attackers":             field(list(participantType), func(w *stellaris.War) any { return participants(w.Attackers) }),
+				"defenders":             field(list(participantType), func(w *stellaris.War) any { return participants(w.Defenders) }),
+			}
+		}),
+	})
+	federationType = gographql.NewObject(gographql.ObjectConfig{
+		Name: "Federation",
+		Fields: gographql.FieldsThunk(func() gographql.Fields {
+			return gographql.Fields{
+				"id":        field(integer, func(f *stellaris.Federation) any { return f.ID }),
+				"name":      field(str, func(f *stellaris.Federation) any { return f.Name }),
+				"type":      field(str, func(f *stellaris.Federation) any { return f.Type }),
+				"level":     field(integer, func(f *stellaris.Federation) any { return f.Level }),
+				"startDate": field(str, func(f *stellaris.Federation) any { return f.StartDate }),
+				"leader":    {Type: countryType, Resolve: ref(gs.Countries, func(f *stellaris.Federation) int { return f.LeaderID })},
+				"members":   {Type: list(countryType), Resolve: refs(gs.Countries, func(f *stellaris.Federation) []int { return f.MemberIDs })},
+			}
+		}),
+	})
+
+	fields := gographql.Fields{}
+	add := func(plural, singular string, l, s *gographql.Field) {
+		fields[plural] = l
+		fields[singular] = s
+	}
+	l, s := collection(countryType, gs.Countries)
+	add("countries", "country", l, s)
+	l, s = collection(speciesType, gs.Species)
+	add("species", "speciesById", l, s)
+	l, s = collection(planetType, gs.Planets)
+	add("planets", "planet", l, s)
+	l, s = collection(popType, gs.Pops)
+	add("pops", "pop", l, s)
+	l, s = collection(leaderType, gs.Leaders)
+	add("leaders", "leader", l, s)
+	l, s = collection(fleetType, gs.Fleets)
+	add("fleets", "fleet", l, s)
+	l, s = collection(shipType, gs.Ships)
+	add("ships", "ship", l, s)
+	l, s = collection(warType, gs.Wars)
+	add("wars", "war", l, s)
+	l, s = collection(federationType, gs.Federations)
+	add("federations", "federation", l, s)
+	return gographql.NewSchema(gographql.SchemaConfig{
+		Query: gographql.NewObject(gographql.ObjectConfig{Name: "Query", Fields: fields}),
+	})
+}
@@ -0,0 +1,101 @@
+package graphql_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/graphql"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+func TestSchema(t *testing.T) {
+	gs := stellaris.NewGamestate(testutil.LoadGamestate(t))
+	schema, err := graphql.NewSchema(gs)
+	require.NoError(t, err)
+	query := func(t *testing.T, q string) string {
+		r := graphql.Do(schema, q, "", nil)
+		require.Empty(t, r.Errors)
+		data, err := json.Marshal(r.Data)
+		require.NoError(t, err)
+		return string(data)
+	}
+	t.Run("should return collections", func(t *testing.T) {
+		got := query(t, `{ countries { id name } }`)
+		assert.JSONEq(t, `{"countries": [
+			{"id": 0, "name": "Blooms of Gaea"},
+			{"id": 1, "name": "SPEC_Human_adj Commonwealth"},
+			{"id": 2, "name": "Kel-Azaan Hegemony"}
+		]}`, got)
+	})
+	t.Run("should return entities by ID", func(t *testing.T) {
+		got := query(t, `{ planet(id: 2) { name owner { name } } missing: planet(id: 99) { name } }`)
+		assert.JSONEq(t, `{"planet": {"name": "NAME_Earth", "owner": {"name": "SPEC_Human_adj Commonwealth"}}, "missing": null}`, got)
+		got = query(t, `{ ships(ids: [20, 99, 30]) { name leader { name } } }`)
+		assert.JSONEq(t, `{"ships": [{"name": "Bloom I", "leader": {"name": "Jorg Xu"}}, {"name": "Zealot", "leader": null}]}`, got)
+	})
+	t.Run("should resolve references across collections", func(t *testing.T) {
+		got := query(t, `{ federations { name members { name planets { name pops { species { name } } } } } }`)
+		assert.JSONEq(t, `{"federations": [{"name": "Green Alliance", "members": [
+			{"name": "Blooms of Gaea", "planets": [
+				{"name": "Gaea", "pops": [{"species": {"name": "Gaeans"}}, {"species": {"name": "Gaeans"}}]},
+				{"name": "Gaea II", "pops": []}
+			]},
+			{"name": "SPEC_Human_adj Commonwealth", "planets": [
+				{"name": "NAME_Earth", "pops": [{"species": {"name": "SPEC_Human"}}]}
+			]}
+		]}]}`, got)
+	})
+	t.Run("should resolve fleets and wars", func(t *testing.T) {
+		got := query(t, `{ country(id: 2) { fleets { name ships { name } } wars { name attackers { callType country { id } } } } }`)
+		assert.JSONEq(t, `{"country": {
+			"fleets": [{"name": "Kel Starbase", "ships": [{"name": "Kel Station"}]}, {"name": "Hegemony Armada", "ships": [{"name": "Zealot"}]}],
+			"wars": [{"name": "Gaean-Kel War", "attackers": [{"callType": "primary", "country": {"id": 0}}, {"callType": "alliance", "country": {"id": 1}}]}]
+		}}`, got)
+	})
+	t.Run("should return null for references which are not set", func(t *testing.T) {
+		got := query(t, `{ country(id: 2) { federation { name } } planet(id: 5) { owner { name } } }`)
+		assert.JSONEq(t, `{"country": {"federation": null}, "planet": {"owner": null}}`, got)
+	})
+}
+
+func TestHandler(t *testing.T) {
+	h, err := graphql.NewHandler(stellaris.NewGamestate(testutil.LoadGamestate(t)))
+	require.NoError(t, err)
+	t.Run("should accept queries with POST", func(t *testing.T) {
+		body := `{"query": "query($id: Int!) { country(id: $id) { name } }", "variables": {"id": 0}}`
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"data": {"country": {"name": "Blooms of Gaea"}}}`, rec.Body.String())
+	})
+	t.Run("should accept queries with GET", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape("{ war(id: 0) { name } }"), nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"data": {"war": {"name": "Gaean-Kel War"}}}`, rec.Body.String())
+	})
+	t.Run("should return errors for invalid queries", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape("{ unknown }"), nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.NotEmpty(t, got["errors"])
+	})
+	t.Run("should reject other methods", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/graphql", nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
@@ -0,0 +1,195 @@
+// Package index builds an index of the save games in a directory.
+//
+// The index contains the file information and the information from the meta file
+// of each save game, which allows to quickly find saves, e.g. by game version or in-game date.
+package index
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// Extension is the file extension of save games.
+const Extension = ".sav"
+
+// Entry represents a save game in the index.
+type Entry struct {
+	Path          string    `json:"path"`
+	Size          int64     `json:"size"`
+	ModTime       time.Time `json:"mod_time"`
+	Name          string    `json:"name"`
+	Date          string    `json:"date"`
+	Version       string    `json:"version"`
+	VersionNumber string    `json:"version_number"`
+	DLCs          []string  `json:"dlcs"`
+	Ironman       bool      `json:"ironman"`
+	// Error is set when the save game could not be read.
+	Error string `json:"error,omitempty"`
+}
+
+// Build returns the index for all save games in dir and it's sub directories ordered by path.
+// Save games which can not be read are included with an error.
+// The same applies to sub directories which can not be read, which are skipped.
+func Build(dir string) ([]Entry, error) {
+	var entries []Entry
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			entries = append(entries, Entry{Path: path, Error: err.Error()})
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), Extension) {
+			return nil
+		}
+		e := Entry{Path: path}
+		info, err := d.Info()
+		if err != nil {
+			e.Error = err.Error()
+		} else {
+			e.Size = info.Size()
+			e.ModTime = info.ModTime().UTC()
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Reading the meta files in parallel, since the index can contain thousands of save games.
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+	for i := range entries {
+		if entries[i].Error != "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(e *Entry) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			si, err := stellaris.ReadSaveInfo(e.Path)
+			if err != nil {
+				e.Error = err.Error()
+				return
+			}
+			e.Name = si.Name
+			e.Date = si.Date
+			e.Version = si.Version
+			e.VersionNumber = si.VersionNumber()
+			e.DLCs = si.RequiredDLCs
+			e.Ironman = si.Ironman
+		}(&entries[i])
+	}
+	wg.Wait()
+	return entries, nil
+}
+
+// WriteJSON writes entries as JSON to w.
+func WriteJSON(w io.Writer, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(entries)
+}
+
+const schemaSQL = `
+CREATE TABLE saves (
+	path TEXT PRIMARY KEY,
+	size INTEGER NOT NULL,
+	mod_time TEXT NOT NULL,
+	name TEXT,
+	date TEXT,
+	version TEXT,
+	version_number TEXT,
+	ironman INTEGER,
+	error TEXT
+);
+CREATE INDEX saves_date ON saves(date);
+CREATE INDEX saves_version_number ON saves(version_number);
+CREATE TABLE save_dlcs (
+	path TEXT NOT NULL REFERENCES saves(path),
+	dlc TEXT NOT NULL
+);
+CREATE INDEX save_dlcs_path ON save_dlcs(path);
+`
+
+// WriteSQLite writes entries into a new SQLite database at path.
+// An existing file at path will be replaced.
+// The database is written to a temporary file first,
+// so an existing file is kept when writing fails.
+func WriteSQLite(path string, entries []Entry) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := writeSQLite(tmp, entries); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func writeSQLite(path string, entries []Entry) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(schemaSQL); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		var errText any
+		if e.Error != "" {
+			errText = e.Error
+		}
+		_, err := tx.Exec(
+			"INSERT INTO saves (path, size, mod_time, name, date, version, version_number, ironman, error) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			e.Path, e.Size, e.ModTime.Format(time.RFC3339), e.Name, e.Date, e.Version, e.VersionNumber, e.Ironman, errText,
+		)
+		if err != nil {
+			return err
+		}
+		for _, dlc := range e.DLCs {
+			if _, err := tx.Exec("INSERT INTO save_dlcs (path, dlc) VALUES (?, ?)", e.Path, dlc); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return db.Close()
+}
@@ -0,0 +1,142 @@
+package index_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/index"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+)
+
+func TestBuild(t *testing.T) {
+	dir := makeSaveDir(t)
+	entries, err := index.Build(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	t.Run("should find save games recursively ordered by path", func(t *testing.T) {
+		assert.Equal(t, filepath.Join(dir, "a.sav"), entries[0].Path)
+		assert.Equal(t, filepath.Join(dir, "broken.sav"), entries[1].Path)
+		assert.Equal(t, filepath.Join(dir, "sub", "b.SAV"), entries[2].Path)
+	})
+	t.Run("should read information from meta file", func(t *testing.T) {
+		e := entries[0]
+		assert.Equal(t, "Blooms of Gaea 2", e.Name)
+		assert.Equal(t, "2415.06.06", e.Date)
+		assert.Equal(t, "Andromeda v3.12.5", e.Version)
+		assert.Equal(t, "3.12.5", e.VersionNumber)
+		assert.True(t, e.Ironman)
+		assert.Contains(t, e.DLCs, "Utopia")
+		assert.Positive(t, e.Size)
+		assert.False(t, e.ModTime.IsZero())
+		assert.Empty(t, e.Error)
+	})
+	t.Run("should report broken save games", func(t *testing.T) {
+		assert.NotEmpty(t, entries[1].Error)
+	})
+}
+
+func TestBuildUnreadableDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("directories are always readable for root")
+	}
+	dir := makeSaveDir(t)
+	locked := filepath.Join(dir, "locked")
+	require.NoError(t, os.Mkdir(locked, 0755))
+	makeSaveFile(t, filepath.Join(locked, "c.sav"))
+	require.NoError(t, os.Chmod(locked, 0))
+	t.Cleanup(func() { os.Chmod(locked, 0755) })
+	entries, err := index.Build(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, locked, entries[2].Path)
+	assert.NotEmpty(t, entries[2].Error)
+	assert.Equal(t, "Blooms of Gaea 2", entries[3].Name)
+}
+
+func TestWriteJSON(t *testing.T) {
+	entries, err := index.Build(makeSaveDir(t))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, index.WriteJSON(&buf, entries))
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Len(t, got, 3)
+	assert.Equal(t, "3.12.5", got[0]["version_number"])
+	assert.NotContains(t, got[0], "error")
+}
+
+func TestWriteSQLite(t *testing.T) {
+	entries, err := index.Build(makeSaveDir(t))
+	require.NoError(t, err)
+	dir := t.TempDir()
+	p := filepath.Join(dir, "index.db")
+	require.NoError(t, os.WriteFile(p, []byte("old"), 0644))
+	require.NoError(t, index.WriteSQLite(p, entries))
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+	db, err := sql.Open("sqlite", p)
+	require.NoError(t, err)
+	defer db.Close()
+	var n int
+	err = db.QueryRow("SELECT COUNT(*) FROM saves WHERE version_number LIKE '3.12.%' AND date > '2400'").Scan(&n)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	err = db.QueryRow("SELECT COUNT(*) FROM save_dlcs WHERE dlc = 'Utopia'").Scan(&n)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	err = db.QueryRow("SELECT COUNT(*) FROM saves WHERE error IS NOT NULL").Scan(&n)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestWriteSQLiteFailure(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "index.db")
+	require.NoError(t, os.WriteFile(p, []byte("old"), 0644))
+	entries := []index.Entry{{Path: "a.sav"}, {Path: "a.sav"}}
+	require.Error(t, index.WriteSQLite(p, entries))
+	t.Run("should keep existing file", func(t *testing.T) {
+		got, err := os.ReadFile(p)
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(got))
+	})
+	t.Run("should remove temporary file", func(t *testing.T) {
+		files, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, files, 1)
+	})
+}
+
+// makeSaveDir creates a directory with save games and returns it's path.
+func makeSaveDir(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	makeSaveFile(t, filepath.Join(dir, "a.sav"))
+	makeSaveFile(t, filepath.Join(dir, "sub", "b.SAV"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.sav"), []byte("invalid"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("other"), 0644))
+	return dir
+}
+
+func makeSaveFile(t *testing.T, p string) {
+	data, err := os.ReadFile(testutil.Path("meta"))
+	require.NoError(t, err)
+	f, err := os.Create(p)
+	require.NoError(t, err)
+	defer f.Close()
+	w := zip.NewWriter(f)
+	fw, err := w.Create("meta")
+	require.NoError(t, err)
+	_, err = fw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
@@ -0,0 +1,169 @@
+// Package parquet exports collections of the gamestate of save files as Apache Parquet files.
+//
+// Each collection is flattened into a table as described in package table
+// and written into a directory per collection with Hive style partitions for the save,
+// e.g. countries/save=Blooms%20of%20Gaea%202/date=2415.06.06/data.parquet.
+// This allows querying many saves at once, e.g. with DuckDB:
+//
+//	SELECT * FROM read_parquet('out/countries/**/*.parquet', hive_partitioning = true, union_by_name = true);
+//
+// The columns of a collection can differ between saves, because they are the keys found in it's entities.
+// But the type of a column does not depend on the values of a save,
+// so the files of many saves can be read together with union_by_name:
+// The known numeric fields of [stellaris.Collections] are stored as DOUBLE, known flags as BOOLEAN
+// and all other columns as STRING. All columns except the ID are optional.
+// References which are not set are stored as null.
+package parquet
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+
+	goparquet "github.com/parquet-go/parquet-go"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/table"
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// Export writes the collections of the gamestate as Parquet files into dir
+// and returns the paths of the written files.
+// The partitions are taken from the name and date of the save in meta.
+// Collections which do not exist in the gamestate are skipped.
+func Export(dir string, meta, gamestate map[string][]any, collections []stellaris.Collection) ([]string, error) {
+	name, _ := tree.Get(meta, "name")
+	date, _ := tree.Get(meta, "date")
+	save, ok1 := name.(string)
+	day, ok2 := date.(string)
+	if !ok1 || !ok2 || save == "" || day == "" {
+		return nil, errors.New("meta: name or date not found")
+	}
+	partition := filepath.Join("save="+url.PathEscape(save), "date="+url.PathEscape(day))
+	var paths []string
+	for _, c := range collections {
+		if _, ok := tree.Object(gamestate, c.Section); !ok {
+			continue
+		}
+		t, err := table.New(gamestate, c.Section, table.Options{})
+		if err != nil {
+			return nil, err
+		}
+		p := filepath.Join(dir, c.Name, partition, "data.parquet")
+		if err := writeTable(p, c, t); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// writeTable writes table t of collection c as Parquet file to path.
+func writeTable(path string, c stellaris.Collection, t *table.Table) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	schema := newSchema(c, t)
+	// The fields of the schema are sorted by name, which can differ from the order of the table.
+	index := make(map[string]int)
+	for i, name := range t.Columns {
+		index[name] = i
+	}
+	fields := schema.Fields()
+	refs := make([]bool, len(fields))
+	for i, field := range fields {
+		f, ok := findField(c, field.Name())
+		refs[i] = ok && f.Ref != ""
+	}
+	w := goparquet.NewWriter(f, schema, goparquet.Compression(&goparquet.Snappy))
+	rows := make([]goparquet.Row, 0, len(t.Rows))
+	for _, r := range t.Rows {
+		row := make(goparquet.Row, len(fields))
+		for i, field := range fields {
+			row[i] = columnValue(field, refs[i], r[index[field.Name()]], i)
+		}
+		rows = append(rows, row)
+	}
+	if _, err := w.WriteRows(rows); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// newSchema returns the schema for table t of collection c.
+func newSchema(c stellaris.Collection, t *table.Table) *goparquet.Schema {
+	g := goparquet.Group{"id": goparquet.Leaf(goparquet.Int64Type)}
+	for _, name := range t.Columns[1:] {
+		g[name] = goparquet.Optional(columnNode(c, name))
+	}
+	return goparquet.NewSchema("row", g)
+}
+
+// columnNode returns the node for a column of collection c.
+func columnNode(c stellaris.Collection, column string) goparquet.Node {
+	f, ok := findField(c, column)
+	if !ok {
+		return goparquet.String()
+	}
+	switch f.Type {
+	case stellaris.IntField, stellaris.FloatField:
+		return goparquet.Leaf(goparquet.DoubleType)
+	case stellaris.BoolField:
+		return goparquet.Leaf(goparquet.BooleanType)
+	}
+	return goparquet.String()
+}
+
+// findField returns the known field of collection c for a column and reports whether it was found.
+// The columns of a table are named after the paths of the values in the entities.
+func findField(c stellaris.Collection, column string) (stellaris.Field, bool) {
+	for _, f := range c.Fields {
+		if slices.Contains(f.Paths(), column) {
+			return f, true
+		}
+	}
+	return stellaris.Field{}, false
+}
+
+// columnValue returns the value v for a field at column index i.
+// Values which do not match the type of a DOUBLE or BOOLEAN field,
+// e.g. multiple joined values, are stored as null.
+// The same goes for references which are not set, when ref is true.
+func columnValue(field goparquet.Field, ref bool, v any, i int) goparquet.Value {
+	if field.Name() == "id" {
+		return goparquet.Int64Value(int64(v.(float64))).Level(0, 0, i)
+	}
+	null := goparquet.Value{}.Level(0, 0, i)
+	if v == nil {
+		return null
+	}
+	switch field.Type().Kind() {
+	case goparquet.Double:
+		x, ok := v.(float64)
+		if !ok {
+			return null
+		}
+		if _, ok := stellaris.ParseID(x); ref && !ok {
+			return null
+		}
+		return goparquet.DoubleValue(x).Level(0, 1, i)
+	case goparquet.Boolean:
+		x, ok := v.(bool)
+		if !ok {
+			return null
+		}
+		return goparquet.BooleanValue(x).Level(0, 1, i)
+	}
+	return goparquet.ByteArrayValue([]byte(table.Format(v))).Level(0, 1, i)
+}
@@ -0,0 +1,146 @@
+package parquet_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	goparquet "github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/parquet"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+func TestExport(t *testing.T) {
+	meta := testutil.Load(t, "meta")
+	gamestate := testutil.LoadGamestate(t)
+	dir := t.TempDir()
+	paths, err := parquet.Export(dir, meta, gamestate, stellaris.Collections)
+	require.NoError(t, err)
+	t.Run("should write file for each collection with partitions", func(t *testing.T) {
+		assert.Len(t, paths, len(stellaris.Collections))
+		p := filepath.Join(dir, "countries", "save=Blooms%20of%20Gaea%202", "date=2415.06.06", "data.parquet")
+		assert.Contains(t, paths, p)
+		assert.FileExists(t, p)
+	})
+	t.Run("should infer schema and write rows", func(t *testing.T) {
+		rows := readFile(t, filepath.Join(dir, "countries", "save=Blooms%20of%20Gaea%202", "date=2415.06.06", "data.parquet"))
+		require.Len(t, rows, 3)
+		assert.Equal(t, int64(0), rows[0]["id"])
+		assert.Equal(t, 12500.5, rows[0]["military_power"])
+		assert.Equal(t, "Blooms of Gaea", rows[0]["name.key"])
+		assert.Equal(t, "1;4", rows[0]["owned_planets"])
+		assert.Nil(t, rows[2]["federation"])
+	})
+	t.Run("should store booleans", func(t *testing.T) {
+		rows := readFile(t, filepath.Join(dir, "fleets", "save=Blooms%20of%20Gaea%202", "date=2415.06.06", "data.parquet"))
+		require.Len(t, rows, 5)
+		assert.Equal(t, true, rows[3]["station"])
+	})
+	t.Run("should return error when meta is incomplete", func(t *testing.T) {
+		_, err := parquet.Export(dir, map[string][]any{}, gamestate, stellaris.Collections)
+		assert.Error(t, err)
+	})
+}
+
+func TestExportSchema(t *testing.T) {
+	dir := t.TempDir()
+	meta := testutil.Load(t, "meta")
+	_, err := parquet.Export(dir, meta, testutil.LoadGamestate(t), stellaris.Collections)
+	require.NoError(t, err)
+	// A later save where no country has a military power and the fleet flags are unknown
+	meta2 := testutil.Load(t, "meta")
+	meta2["date"] = []any{"2420.01.01"}
+	gamestate2 := testutil.LoadGamestate(t)
+	for _, v := range gamestate2["country"][0].(map[string][]any) {
+		if c, ok := v[0].(map[string][]any); ok {
+			c["military_power"] = []any{nil}
+		}
+	}
+	for _, v := range gamestate2["fleet"][0].(map[string][]any) {
+		if f, ok := v[0].(map[string][]any); ok {
+			f["station"] = []any{"unknown"}
+		}
+	}
+	_, err = parquet.Export(dir, meta2, gamestate2, stellaris.Collections)
+	require.NoError(t, err)
+	for _, c := range stellaris.Collections {
+		p1 := filepath.Join(dir, c.Name, "save=Blooms%20of%20Gaea%202", "date=2415.06.06", "data.parquet")
+		p2 := filepath.Join(dir, c.Name, "save=Blooms%20of%20Gaea%202", "date=2420.01.01", "data.parquet")
+		assert.Equal(t, readSchema(t, p1), readSchema(t, p2), c.Name)
+	}
+}
+
+func TestExportUnsetIDs(t *testing.T) {
+	dir := t.TempDir()
+	gamestate := testutil.LoadGamestate(t)
+	planets := gamestate["planets"][0].(map[string][]any)["planet"][0].(map[string][]any)
+	planets["1"][0].(map[string][]any)["controller"] = []any{4294967295.0}
+	_, err := parquet.Export(dir, testutil.Load(t, "meta"), gamestate, stellaris.Collections)
+	require.NoError(t, err)
+	rows := readFile(t, filepath.Join(dir, "planets", "save=Blooms%20of%20Gaea%202", "date=2415.06.06", "data.parquet"))
+	for _, r := range rows {
+		if r["id"] == int64(1) {
+			assert.Nil(t, r["controller"])
+			assert.NotNil(t, r["owner"])
+			return
+		}
+	}
+	t.Fatal("planet not found")
+}
+
+// readSchema returns the schema of a Parquet file.
+func readSchema(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	st, err := f.Stat()
+	require.NoError(t, err)
+	pf, err := goparquet.OpenFile(f, st.Size())
+	require.NoError(t, err)
+	return pf.Schema().String()
+}
+
+// readFile returns the rows of a Parquet file as maps of column names to values.
+func readFile(t *testing.T, path string) []map[string]any {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	st, err := f.Stat()
+	require.NoError(t, err)
+	pf, err := goparquet.OpenFile(f, st.Size())
+	require.NoError(t, err)
+	fields := pf.Schema().Fields()
+	r := goparquet.NewReader(pf)
+	defer r.Close()
+	var rows []map[string]any
+	buf := make([]goparquet.Row, int(pf.NumRows()))
+	n, err := r.ReadRows(buf)
+	if err != nil && err != io.EOF {
+		require.NoError(t, err)
+	}
+	for _, row := range buf[:n] {
+		m := make(map[string]any)
+		for _, v := range row {
+			name := fields[v.Column()].Name()
+			switch {
+			case v.IsNull():
+				m[name] = nil
+			case v.Kind() == goparquet.Int64:
+				m[name] = v.Int64()
+			case v.Kind() == goparquet.Double:
+				m[name] = v.Double()
+			case v.Kind() == goparquet.Boolean:
+				m[name] = v.Boolean()
+			default:
+				m[name] = v.String()
+			}
+		}
+		rows = append(rows, m)
+	}
+	return rows
+}
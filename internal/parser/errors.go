@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyntaxError describes a syntax error in the parsed input.
+type SyntaxError struct {
+	Offset  int64  // byte offset of the offending token
+	Line    int    // line of the offending token
+	Column  int    // column of the offending token
+	Token   string // the offending token
+	Path    string // key path of the enclosing element, e.g. "country.12.modules"
+	Snippet string // excerpt of the input around the offending token
+	Msg     string // description of the error
+}
+
+func (e *SyntaxError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "line %d, column %d", e.Line, e.Column)
+	if e.Path != "" {
+		fmt.Fprintf(&b, " at %s", e.Path)
+	}
+	fmt.Fprintf(&b, ": %s", e.Msg)
+	if e.Snippet != "" {
+		fmt.Fprintf(&b, " near %q", e.Snippet)
+	}
+	return b.String()
+}
@@ -4,16 +4,48 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"slices"
 	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/charset"
 )
 
 var eof = rune(0)
 
+// Max number of recently read runes the lexer keeps for creating snippets.
+const historySize = 256
+
+// Max number of runes on each side of a position shown in a snippet.
+const snippetSize = 40
+
+// position represents the location of a token in the input.
+type position struct {
+	offset int64 // byte offset from the start of the input
+	line   int
+	column int
+}
+
+// readRune is a rune read from the input together with it's size in bytes.
+type readRune struct {
+	ch   rune
+	size int
+}
+
 // lexer represents a lexical scanner.
 type lexer struct {
-	r   *bufio.Reader
-	loc int
+	r        *bufio.Reader
+	encoding charset.Encoding // encoding of the input
+	loc      int              // current line
+	col      int              // column of the last read rune
+	prevCol  int              // column before the last read rune
+	offset   int64            // number of bytes read
+	lastCh   rune             // last read rune
+	lastSize int              // size of the last read rune in bytes
+	history  []readRune       // recently read runes
+	start    position         // position of the last returned token
 }
 
 // newLexer returns a new instance of lexer
@@ -25,20 +57,27 @@ func newLexer(r io.Reader) *lexer {
 func (l *lexer) lex() (token, error) {
 	// Read the next rune.
 	for {
+		l.start = position{offset: l.offset, line: l.loc, column: l.col + 1}
 		ch, err := l.read()
 		if err != nil {
 			return token{}, err
 		}
 		if unicode.IsSpace(ch) {
-			l.unread()
-			l.consumeWhitespace()
+			if err := l.unread(); err != nil {
+				return token{}, err
+			}
+			if err := l.consumeWhitespace(); err != nil {
+				return token{}, err
+			}
 			continue
 		}
 		if ch == '"' {
 			return l.scanString()
 		}
 		if unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '-' || ch == '@' {
-			l.unread()
+			if err := l.unread(); err != nil {
+				return token{}, err
+			}
 			return l.scanWord()
 		}
 		switch ch {
@@ -57,23 +96,159 @@ func (l *lexer) lex() (token, error) {
 
 // read reads and returns the next rune from the buffered reader or the EOF rune.
 func (l *lexer) read() (rune, error) {
-	ch, _, err := l.r.ReadRune()
+	if l.offset == 0 && l.encoding != charset.Windows1252 {
+		if err := l.skipBOM(); err != nil {
+			return 0, err
+		}
+	}
+	ch, size, err := l.decodeRune()
 	if err == io.EOF {
+		l.lastCh = eof
 		return eof, nil
 	} else if err != nil {
 		return 0, err
 	}
+	l.lastCh = ch
+	l.lastSize = size
+	l.offset += int64(size)
+	if len(l.history) >= 2*historySize {
+		l.history = append(l.history[:0], l.history[len(l.history)-historySize:]...)
+	}
+	l.history = append(l.history, readRune{ch, size})
+	l.prevCol = l.col
+	if ch == '\n' {
+		l.loc++
+		l.col = 0
+	} else {
+		l.col++
+	}
 	return ch, nil
 }
 
+// decodeRune reads the next rune from the buffered reader in the encoding of the input.
+func (l *lexer) decodeRune() (rune, int, error) {
+	if l.encoding == charset.Windows1252 {
+		b, err := l.r.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		return charset.DecodeWindows1252(b), 1, nil
+	}
+	ch, size, err := l.r.ReadRune()
+	if err != nil {
+		return 0, 0, err
+	}
+	if ch == utf8.RuneError && size == 1 && l.encoding == charset.Auto {
+		// Not valid UTF-8, so we assume it is Windows-1252
+		if err := l.r.UnreadRune(); err != nil {
+			return 0, 0, err
+		}
+		b, err := l.r.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		return charset.DecodeWindows1252(b), 1, nil
+	}
+	return ch, size, nil
+}
+
+// skipBOM skips the byte order mark at the start of UTF-8 encoded input.
+func (l *lexer) skipBOM() error {
+	b, err := l.r.Peek(len(charset.BOM))
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if !bytes.Equal(b, charset.BOM) {
+		return nil
+	}
+	n, err := l.r.Discard(len(charset.BOM))
+	l.offset += int64(n)
+	return err
+}
+
 // unread places the previously read rune back on the reader.
 func (l *lexer) unread() error {
-	if err := l.r.UnreadRune(); err != nil {
+	if l.lastCh == eof {
+		return nil
+	}
+	var err error
+	if l.lastSize == 1 {
+		err = l.r.UnreadByte()
+	} else {
+		err = l.r.UnreadRune()
+	}
+	if err != nil {
 		return err
 	}
+	if l.lastCh == '\n' {
+		l.loc--
+	}
+	l.col = l.prevCol
+	l.offset -= int64(l.lastSize)
+	l.history = l.history[:len(l.history)-1]
 	return nil
 }
 
+// snippet returns an excerpt of the line around the given byte offset.
+// The offset must be within the recently read input.
+func (l *lexer) snippet(offset int64) string {
+	i := len(l.history)
+	end := l.offset
+	for i > 0 && end > offset {
+		i--
+		end -= int64(l.history[i].size)
+	}
+	if end != offset {
+		return ""
+	}
+	before := make([]rune, 0, snippetSize)
+	for _, x := range l.history[max(0, i-snippetSize):i] {
+		before = append(before, x.ch)
+	}
+	after := make([]rune, 0, snippetSize)
+	for _, x := range l.history[i:] {
+		after = append(after, x.ch)
+	}
+	if len(after) < snippetSize {
+		ahead, _ := l.r.Peek((snippetSize - len(after)) * utf8.UTFMax)
+		after = append(after, l.decode(ahead)...)
+	}
+	after = after[:min(len(after), snippetSize)]
+	for j := len(before) - 1; j >= 0; j-- {
+		if before[j] == '\n' {
+			before = before[j+1:]
+			break
+		}
+	}
+	if j := slices.Index(after, '\n'); j != -1 {
+		after = after[:j]
+	}
+	return strings.TrimSpace(string(before) + string(after))
+}
+
+// decode returns the runes from b in the encoding of the input.
+// An incomplete rune at the end of b is ignored.
+func (l *lexer) decode(b []byte) []rune {
+	rr := make([]rune, 0, len(b))
+	for len(b) > 0 {
+		if l.encoding == charset.Windows1252 {
+			rr = append(rr, charset.DecodeWindows1252(b[0]))
+			b = b[1:]
+			continue
+		}
+		if !utf8.FullRune(b) {
+			break
+		}
+		ch, size := utf8.DecodeRune(b)
+		if ch == utf8.RuneError && size == 1 && l.encoding == charset.Auto {
+			ch = charset.DecodeWindows1252(b[0])
+		}
+		rr = append(rr, ch)
+		b = b[size:]
+	}
+	return rr
+}
+
 // consumeWhitespace consumes all whitespace from the reader.
 func (l *lexer) consumeWhitespace() error {
 	for {
@@ -85,11 +260,7 @@ func (l *lexer) consumeWhitespace() error {
 			break
 		}
 		if !unicode.IsSpace(ch) {
-			l.unread()
-			break
-		}
-		if ch == '\n' {
-			l.loc++
+			return l.unread()
 		}
 	}
 	return nil
@@ -115,7 +286,9 @@ func (l *lexer) scanWord() (token, error) {
 		if ch == eof {
 			break
 		} else if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) && ch != '_' && ch != '-' && ch != '.' && ch != ':' {
-			l.unread()
+			if err := l.unread(); err != nil {
+				return token{}, err
+			}
 			break
 		} else {
 			_, err := buf.WriteRune(ch)
@@ -156,6 +329,7 @@ func (l *lexer) scanWord() (token, error) {
 }
 
 // scanString returns a string token from the scanned input.
+// When the input ends before the closing quote it returns an unterminated token.
 func (l *lexer) scanString() (token, error) {
 	var buf bytes.Buffer
 	var escape bool
@@ -165,7 +339,7 @@ func (l *lexer) scanString() (token, error) {
 			return token{}, err
 		}
 		if ch == eof {
-			break
+			return token{unterminated, buf.String()}, nil
 		}
 		if !escape && ch == '\\' {
 			escape = true
@@ -1,9 +1,12 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -31,6 +34,7 @@ func TestSingleTokens(t *testing.T) {
 		{"indeterminable", token{identifier, "indeterminable"}},
 		{`"one \"two\" three"`, token{str, "one \"two\" three"}},
 		{`"one \\ two"`, token{str, "one \\ two"}},
+		{`"one two`, token{unterminated, "one two"}},
 		{"one:two", token{identifier, "one:two"}},
 		{"@one", token{identifier, "@one"}},
 	}
@@ -116,3 +120,42 @@ func TestSpecialFeatures(t *testing.T) {
 		assert.Equal(t, 2, s.loc)
 	})
 }
+
+func TestTokenPosition(t *testing.T) {
+	in := strings.NewReader("alpha=1\n  bravo={\n\"x\"}")
+	s := newLexer(in)
+	got := make([]position, 0)
+	for {
+		token, _ := s.lex()
+		if token.typ == endOfFile {
+			break
+		}
+		got = append(got, s.start)
+	}
+	want := []position{
+		{0, 1, 1}, {5, 1, 6}, {6, 1, 7},
+		{10, 2, 3}, {15, 2, 8}, {16, 2, 9},
+		{18, 3, 1}, {21, 3, 4},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestLexerReadErrors(t *testing.T) {
+	errRead := errors.New("read failed")
+	cases := []string{"", " ", "alpha", "\"alpha", "12", "alpha=\"bravo"}
+	for _, in := range cases {
+		t.Run(fmt.Sprintf("in: %s", in), func(t *testing.T) {
+			r := io.MultiReader(strings.NewReader(in), iotest.ErrReader(errRead))
+			l := newLexer(r)
+			var err error
+			for range 5 {
+				var tok token
+				tok, err = l.lex()
+				if err != nil || tok.typ == endOfFile {
+					break
+				}
+			}
+			assert.ErrorIs(t, err, errRead)
+		})
+	}
+}
@@ -5,21 +5,56 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/charset"
 )
 
 var emptyObject = struct{}{}
 
+// posToken is a token together with it's position in the input.
+type posToken struct {
+	token
+	pos position
+}
+
 // Parser represents a parser for Paradox save files.
 type Parser struct {
+	// Lenient enables the error recovery mode.
+	// In this mode syntax errors are recorded as diagnostics
+	// and the parser skips to the next recoverable point instead of aborting.
+	Lenient bool
+	// AllowUnbalanced disables the checks for balanced brackets.
+	// When enabled the parser ignores extra closing brackets
+	// and treats the end of the input as closing all open brackets.
+	// This can be used for files which are known to contain such quirks.
+	AllowUnbalanced bool
+	// Encoding is the character encoding of the input.
+	// The default detects UTF-8 with or without BOM and falls back to Windows-1252.
+	Encoding charset.Encoding
+
 	// Provides a stream of tokens
 	lex *lexer
 	// Stack of latest tokens so we can go back
-	ts stack[token]
+	ts stack[posToken]
+	// Keys of the elements currently being parsed
+	path []string
+	// Current nesting level of brackets
+	depth int
+	// Whether the unexpected end of the input has already been reported
+	eofReported bool
+	// Syntax errors found in lenient mode
+	diagnostics []*SyntaxError
 }
 
 // NewParser takes a reader and returns a new instance of Parser.
 func NewParser(r io.Reader) *Parser {
-	return &Parser{lex: newLexer(r), ts: newStack[token](3)}
+	return &Parser{lex: newLexer(r), ts: newStack[posToken](3)}
+}
+
+// Diagnostics returns the syntax errors found while parsing in lenient mode.
+func (p *Parser) Diagnostics() []*SyntaxError {
+	return p.diagnostics
 }
 
 // Parse parsed a Paradox save file and returns it's contents.
@@ -27,12 +62,26 @@ func NewParser(r io.Reader) *Parser {
 // Here is how the parser deals with some particulars of the paradox format:
 // - The format allows multiple values for a key, so the parser returns a nested map of keys to value slices.
 // - All keys are converted to strings, including keywords and numbers
-// - The keywords "none" and "not_set" are converted to nil (when used as values)
+// - The keywords "none" and "not_set" are converted to nil (when used as values or array elements)
 // - The keywords "yes" and "no" are converted to bool
 // - All numbers are converted to float64
 // - Arrays will be returned as slices
+// - Blocks are objects when they start with a key followed by an equal sign, e.g. {a=1}, and arrays otherwise, e.g. {a 1} or {{a=1}}, at any nesting level
+// - Arrays with mixed content will be returned as []any
 // - Arrays can also be empty
+//
+// Syntax errors in the input are returned as [*SyntaxError].
+// This includes unbalanced brackets, e.g. from truncated files,
+// unless [Parser.AllowUnbalanced] is enabled.
+// In lenient mode Parse returns a best-effort result for input with syntax errors
+// and reports those errors through [Parser.Diagnostics].
 func (p *Parser) Parse() (map[string][]any, error) {
+	p.lex.encoding = p.Encoding
+	return p.parseObject()
+}
+
+// parseObject parses key/value pairs up to the end of the current nesting level.
+func (p *Parser) parseObject() (map[string][]any, error) {
 	result := make(map[string][]any)
 loop:
 	for {
@@ -45,14 +94,34 @@ loop:
 			return nil, err
 		}
 		switch tok.typ {
-		case endOfFile, bracketsClose:
+		case endOfFile:
+			if err := p.unexpectedEOF(tok); err != nil {
+				return nil, err
+			}
+			break loop
+		case bracketsClose:
+			if p.depth == 0 && !p.AllowUnbalanced {
+				if err := p.syntaxError(tok, "unexpected closing bracket"); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if p.depth == 0 {
+				continue // ignore extra closing bracket
+			}
 			break loop
 		case identifier, str:
 			key = tok.value.(string)
 		case integer:
 			key = strconv.Itoa(tok.value.(int))
 		default:
-			return nil, p.makeError("found %v, expected some kind of key", tok)
+			if err := p.syntaxError(tok, "found %v, expected some kind of key", tok); err != nil {
+				return nil, err
+			}
+			if err := p.recoverToKey(tok); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
 		// Next is usually an equal sign. If it is omitted we assume there is one.
@@ -69,150 +138,31 @@ loop:
 		if err != nil {
 			return nil, err
 		}
+		p.path = append(p.path, key)
 		switch tok.typ {
 		case str, float, boolean:
 			value = tok.value
 		case integer:
 			value = float64(tok.value.(int))
 		case identifier:
-			if tok.value == "none" || tok.value == "not_set" {
-				value = nil
-			} else {
-				value = tok.value
-			}
+			value = identifierValue(tok)
 		case bracketsOpen:
-			tok2, err := p.nextToken()
+			x, err := p.parseBlock()
 			if err != nil {
 				return nil, err
 			}
-			switch tok2.typ {
-			case bracketsClose:
-				// Empty object
-				value = emptyObject
-			case bracketsOpen:
-				// Array of objects
-				oo := make([]map[string][]any, 0)
-				for {
-					v2, err := p.Parse()
-					if err != nil {
-						return nil, err
-					}
-					oo = append(oo, v2)
-					tok3, err := p.nextToken()
-					if err != nil {
-						return nil, err
-					}
-					if tok3.typ == bracketsClose {
-						break
-					} else if tok3.typ != bracketsOpen {
-						return nil, p.makeError("unexpected token %v in obj array", tok3)
-					}
-				}
-				value = oo
-			case identifier, str:
-				tok3, err := p.nextToken()
-				if err != nil {
-					return nil, err
-				}
-				if tok3.typ == equalSign {
-					// A regular object
-					p.backup(tok3)
-					p.backup(tok2)
-					x, err := p.Parse()
-					if err != nil {
-						return nil, err
-					}
-					value = x
-				} else {
-					// Array of string
-					p.backup(tok3)
-					p.backup(tok2)
-					ss := make([]string, 0)
-					for {
-						tok3, err := p.nextToken()
-						if err != nil {
-							return nil, err
-						}
-						if tok3.typ == bracketsClose {
-							break
-						}
-						y, ok := tok3.value.(string)
-						if !ok {
-							return nil, p.makeError("found %v, expected type string for array", tok3)
-						}
-						ss = append(ss, y)
-					}
-					value = ss
-				}
-			case integer, float:
-				if tok2.typ == integer {
-					tok3, err := p.nextToken()
-					if err != nil {
-						return nil, err
-					}
-					p.backup(tok3)
-					p.backup(tok2)
-					if tok3.typ == equalSign {
-						// An ID object
-						x, err := p.Parse()
-						if err != nil {
-							return nil, err
-						}
-						value = x
-						break
-					}
-					if tok3.typ == bracketsOpen {
-						return nil, p.makeError("unexpected token: %v", tok3)
-					}
-				} else {
-					p.backup(tok2)
-				}
-				// Array of numbers
-				ff := make([]float64, 0)
-				for {
-					tok3, err := p.nextToken()
-					if err != nil {
-						return nil, err
-					}
-					if tok3.typ == bracketsClose {
-						break
-					}
-					switch tok3.typ {
-					case float:
-						ff = append(ff, tok3.value.(float64))
-					case integer:
-						ff = append(ff, float64(tok3.value.(int)))
-					default:
-						return nil, p.makeError("unexpected token for number array: %v", tok3)
-					}
-				}
-				value = ff
-			case boolean:
-				// Array of boolean
-				p.backup(tok2)
-				ss := make([]bool, 0)
-				for {
-					tok3, err := p.nextToken()
-					if err != nil {
-						return nil, err
-					}
-					if tok3.typ == bracketsClose {
-						break
-					}
-					y, ok := tok3.value.(bool)
-					if !ok {
-						return nil, p.makeError("expected type boolean for boolean array, but got: %v", tok3)
-					}
-					ss = append(ss, y)
-				}
-				value = ss
-			default:
-				return nil, p.makeError("invalid token %v for array", tok2)
-			}
-
+			value = x
 		default:
-			return nil, p.makeError("found %v, expected a value", tok)
+			if err := p.syntaxError(tok, "found %v, expected a value", tok); err != nil {
+				return nil, err
+			}
+			p.path = p.path[:len(p.path)-1]
+			if err := p.recoverToKey(tok); err != nil {
+				return nil, err
+			}
+			continue
 		}
+		p.path = p.path[:len(p.path)-1]
 		if value != emptyObject {
 			result[key] = append(result[key], value)
 		} else {
@@ -222,31 +172,256 @@ loop:
 	return result, nil
 }
 
+// parseBlock parses the contents of a block after it's opening bracket.
+// A block is either an object, an array or empty.
+// It is an object when it starts with a key followed by an equal sign.
+func (p *Parser) parseBlock() (any, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	tok, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	switch tok.typ {
+	case bracketsClose:
+		return emptyObject, nil
+	case endOfFile:
+		if err := p.unexpectedEOF(tok); err != nil {
+			return nil, err
+		}
+		return emptyObject, nil
+	case identifier, str, integer:
+		tok2, err := p.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		p.backup(tok2)
+		p.backup(tok)
+		if tok2.typ == equalSign {
+			// A regular object or an ID object
+			return p.parseObject()
+		}
+	default:
+		p.backup(tok)
+	}
+	return p.parseArray()
+}
+
+// parseArray parses the elements of an array up to it's closing bracket.
+func (p *Parser) parseArray() (any, error) {
+	elements := make([]any, 0)
+	for {
+		tok, err := p.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		var v any
+		switch tok.typ {
+		case bracketsClose:
+			return makeArray(elements), nil
+		case bracketsOpen:
+			p.path = append(p.path, strconv.Itoa(len(elements)))
+			v, err = p.parseBlock()
+			if err != nil {
+				return nil, err
+			}
+			if v == emptyObject {
+				v = make(map[string][]any)
+			}
+			p.path = p.path[:len(p.path)-1]
+		case endOfFile:
+			if err := p.unexpectedEOF(tok); err != nil {
+				return nil, err
+			}
+			return makeArray(elements), nil
+		case str, float, boolean:
+			v = tok.value
+		case identifier:
+			v = identifierValue(tok)
+		case integer:
+			v = float64(tok.value.(int))
+		default:
+			if err := p.syntaxError(tok, "unexpected token %v in array", tok); err != nil {
+				return nil, err
+			}
+			// Recover by skipping the rest of the array
+			if err := p.skipBlock(); err != nil {
+				return nil, err
+			}
+			return makeArray(elements), nil
+		}
+		elements = append(elements, v)
+	}
+}
+
+// identifierValue returns the value of an identifier token.
+// The keywords "none" and "not_set" are converted to nil.
+func identifierValue(tok posToken) any {
+	if tok.value == "none" || tok.value == "not_set" {
+		return nil
+	}
+	return tok.value
+}
+
+// makeArray returns the elements as typed slice when they all have the same type
+// or as []any when the content is mixed.
+func makeArray(elements []any) any {
+	if len(elements) == 0 {
+		return elements
+	}
+	switch elements[0].(type) {
+	case float64:
+		if s, ok := sliceOf[float64](elements); ok {
+			return s
+		}
+	case string:
+		if s, ok := sliceOf[string](elements); ok {
+			return s
+		}
+	case bool:
+		if s, ok := sliceOf[bool](elements); ok {
+			return s
+		}
+	case map[string][]any:
+		if s, ok := sliceOf[map[string][]any](elements); ok {
+			return s
+		}
+	}
+	return elements
+}
+
+// sliceOf converts elements into a slice of type T
+// and reports wether all elements had that type.
+func sliceOf[T any](elements []any) ([]T, bool) {
+	s := make([]T, 0, len(elements))
+	for _, e := range elements {
+		v, ok := e.(T)
+		if !ok {
+			return nil, false
+		}
+		s = append(s, v)
+	}
+	return s, true
+}
+
+// recoverToKey skips tokens after the offending token tok
+// until the next key or the end of the current object.
+func (p *Parser) recoverToKey(tok posToken) error {
+	if tok.typ == bracketsClose || tok.typ == endOfFile {
+		p.backup(tok)
+		return nil
+	}
+	if tok.typ == bracketsOpen {
+		if err := p.skipBlock(); err != nil {
+			return err
+		}
+	}
+	for {
+		tok, err := p.nextToken()
+		if err != nil {
+			return err
+		}
+		switch tok.typ {
+		case endOfFile, bracketsClose:
+			p.backup(tok)
+			return nil
+		case bracketsOpen:
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+		case identifier, str, integer:
+			tok2, err := p.nextToken()
+			if err != nil {
+				return err
+			}
+			p.backup(tok2)
+			if tok2.typ == equalSign {
+				p.backup(tok)
+				return nil
+			}
+		}
+	}
+}
+
+// skipBlock skips all tokens up to and including the closing bracket of the current block.
+func (p *Parser) skipBlock() error {
+	depth := 1
+	for depth > 0 {
+		tok, err := p.nextToken()
+		if err != nil {
+			return err
+		}
+		switch tok.typ {
+		case endOfFile:
+			p.backup(tok)
+			return nil
+		case bracketsOpen:
+			depth++
+		case bracketsClose:
+			depth--
+		}
+	}
+	return nil
+}
+
 // nextToken returns the next token from the underlying scanner.
 // If a token has been unscanned then read that instead.
-func (p *Parser) nextToken() (token, error) {
+func (p *Parser) nextToken() (posToken, error) {
 	// If we have a token on the buffer, then return it.
 	if !p.ts.isEmpty() {
 		tok, err := p.ts.pop()
 		if err != nil {
-			return token{}, nil
+			return posToken{}, err
 		}
 		return tok, nil
 	}
 	// Otherwise read the next token from the scanner.
 	tok, err := p.lex.lex()
 	if err != nil {
-		return token{}, nil
+		return posToken{}, fmt.Errorf("read token at line %d, column %d: %w", p.lex.start.line, p.lex.start.column, err)
 	}
-	return tok, nil
+	pt := posToken{tok, p.lex.start}
+	if tok.typ == unterminated {
+		if err := p.syntaxError(pt, "unexpected end of input, unterminated string"); err != nil {
+			return posToken{}, err
+		}
+		pt.typ = str // keep the partial string in lenient mode
+	}
+	return pt, nil
 }
 
 // backup pushes the a token back onto the stack.
-func (p *Parser) backup(tok token) {
+func (p *Parser) backup(tok posToken) {
 	p.ts.push(tok)
 }
 
-func (p *Parser) makeError(format string, a ...any) error {
-	s := fmt.Sprintf(format, a...)
-	return fmt.Errorf("%s in line %d", s, p.lex.loc)
+// unexpectedEOF reports the end of the input when there are still open brackets.
+// The end of the input is only reported once, even though it closes all nesting levels.
+func (p *Parser) unexpectedEOF(tok posToken) error {
+	if p.depth == 0 || p.AllowUnbalanced || p.eofReported {
+		return nil
+	}
+	p.eofReported = true
+	return p.syntaxError(tok, "unexpected end of input, missing closing bracket")
+}
+
+// syntaxError reports a syntax error at the offending token tok.
+// In strict mode it returns the error as [*SyntaxError].
+// In lenient mode it records the error as diagnostic and returns nil,
+// so the caller can recover and continue.
+func (p *Parser) syntaxError(tok posToken, format string, a ...any) error {
+	err := &SyntaxError{
+		Offset:  tok.pos.offset,
+		Line:    tok.pos.line,
+		Column:  tok.pos.column,
+		Token:   tok.String(),
+		Path:    strings.Join(p.path, "."),
+		Snippet: p.lex.snippet(tok.pos.offset),
+		Msg:     fmt.Sprintf(format, a...),
+	}
+	if !p.Lenient {
+		return err
+	}
+	p.diagnostics = append(p.diagnostics, err)
+	return nil
 }
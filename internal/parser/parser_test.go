@@ -1,10 +1,14 @@
 package parser_test
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
+	"github.com/ErikKalkoken/stellaris-tool/internal/charset"
 	"github.com/ErikKalkoken/stellaris-tool/internal/parser"
 
 	"github.com/stretchr/testify/assert"
@@ -82,6 +86,39 @@ func TestParser(t *testing.T) {
 			"alpha={yes yes no no}",
 			map[string][]any{"alpha": {[]bool{true, true, false, false}}},
 		},
+		// Array with mixed content
+		{
+			"alpha={1 \"two\" three yes}",
+			map[string][]any{"alpha": {[]any{1.0, "two", "three", true}}},
+		},
+		{
+			"alpha={\"first\" 2}",
+			map[string][]any{"alpha": {[]any{"first", 2.0}}},
+		},
+		{
+			"alpha={1 2 {bravo=1}}",
+			map[string][]any{"alpha": {[]any{1.0, 2.0, map[string][]any{"bravo": {1.0}}}}},
+		},
+		{
+			"alpha={{bravo=1} yes}",
+			map[string][]any{"alpha": {[]any{map[string][]any{"bravo": {1.0}}, true}}},
+		},
+		{
+			"alpha={ 1 { 2 3 } }",
+			map[string][]any{"alpha": {[]any{1.0, []float64{2, 3}}}},
+		},
+		{
+			"alpha={ {1 2} {3 4} }",
+			map[string][]any{"alpha": {[]any{[]float64{1, 2}, []float64{3, 4}}}},
+		},
+		{
+			"alpha={ {1 2 3} }",
+			map[string][]any{"alpha": {[]any{[]float64{1, 2, 3}}}},
+		},
+		{
+			"alpha={ {} {bravo=1} }",
+			map[string][]any{"alpha": {[]map[string][]any{{}, {"bravo": {1.0}}}}},
+		},
 		// Objects
 		{
 			"alpha={bravo=3}",
@@ -129,10 +166,10 @@ func TestParser(t *testing.T) {
 				map[string][]any{"1": {map[string][]any{"bravo": {2.0}}}},
 			}},
 		},
-		// Array of objects without equal sign
+		// Array of arrays without equal sign
 		{
 			"alpha={{bravo 42}}",
-			map[string][]any{"alpha": {[]map[string][]any{{"bravo": {42.0}}}}},
+			map[string][]any{"alpha": {[]any{[]any{"bravo", 42.0}}}},
 		},
 		// Date as value which is no string
 		{
@@ -180,6 +217,59 @@ func TestParser(t *testing.T) {
 	}
 }
 
+func TestParserBlocks(t *testing.T) {
+	cases := []struct {
+		in   string
+		want any
+	}{
+		// Identifier as key
+		{"{bravo=42}", map[string][]any{"bravo": {42.0}}},
+		{"{bravo \"x\"}", []string{"bravo", "x"}},
+		{"{bravo 42}", []any{"bravo", 42.0}},
+		{"{bravo 4.2}", []any{"bravo", 4.2}},
+		{"{bravo yes}", []any{"bravo", true}},
+		{"{bravo charlie}", []string{"bravo", "charlie"}},
+		{"{bravo none}", []any{"bravo", nil}},
+		{"{bravo {42}}", []any{"bravo", []float64{42}}},
+		// String as key
+		{"{\"bravo\"=42}", map[string][]any{"bravo": {42.0}}},
+		{"{\"bravo\" \"x\"}", []string{"bravo", "x"}},
+		{"{\"bravo\" 42}", []any{"bravo", 42.0}},
+		{"{\"bravo\" 4.2}", []any{"bravo", 4.2}},
+		{"{\"bravo\" yes}", []any{"bravo", true}},
+		{"{\"bravo\" charlie}", []string{"bravo", "charlie"}},
+		{"{\"bravo\" none}", []any{"bravo", nil}},
+		{"{\"bravo\" {42}}", []any{"bravo", []float64{42}}},
+		// Integer as key
+		{"{1=42}", map[string][]any{"1": {42.0}}},
+		{"{1 \"x\"}", []any{1.0, "x"}},
+		{"{1 42}", []float64{1, 42}},
+		{"{1 4.2}", []float64{1, 4.2}},
+		{"{1 yes}", []any{1.0, true}},
+		{"{1 charlie}", []any{1.0, "charlie"}},
+		{"{1 none}", []any{1.0, nil}},
+		{"{1 {42}}", []any{1.0, []float64{42}}},
+	}
+	for _, tc := range cases {
+		t.Run("value "+tc.in, func(t *testing.T) {
+			got, err := parser.NewParser(strings.NewReader("alpha=" + tc.in)).Parse()
+			if assert.NoError(t, err) {
+				assert.Equal(t, map[string][]any{"alpha": {tc.want}}, got)
+			}
+		})
+		t.Run("nested "+tc.in, func(t *testing.T) {
+			got, err := parser.NewParser(strings.NewReader("alpha={" + tc.in + "}")).Parse()
+			var want any = []any{tc.want}
+			if m, ok := tc.want.(map[string][]any); ok {
+				want = []map[string][]any{m}
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, map[string][]any{"alpha": {want}}, got)
+			}
+		})
+	}
+}
+
 // func TestParserFull(t *testing.T) {
 // 	f, err := os.Open("../.temp/gamestate")
 // 	if err != nil {
@@ -190,3 +280,315 @@ func TestParser(t *testing.T) {
 // 	_, err = p.Parse()
 // 	assert.NoError(t, err)
 // }
+
+func TestParserLenient(t *testing.T) {
+	cases := []struct {
+		in    string
+		want  map[string][]any
+		diags []*parser.SyntaxError
+	}{
+		{
+			"alpha=1 = bravo=2",
+			map[string][]any{"alpha": {1.0}, "bravo": {2.0}},
+			[]*parser.SyntaxError{
+				{Offset: 8, Line: 1, Column: 9, Token: "=", Snippet: "alpha=1 = bravo=2", Msg: "found =, expected some kind of key"},
+			},
+		},
+		{
+			"alpha=# bravo=2",
+			map[string][]any{"bravo": {2.0}},
+			[]*parser.SyntaxError{
+				{Offset: 6, Line: 1, Column: 7, Token: "#", Path: "alpha", Snippet: "alpha=# bravo=2", Msg: "found #, expected a value"},
+			},
+		},
+		{
+			"alpha={\nbravo=1\ncharlie={1 2 = 3}\ndelta=4\n}\necho=5",
+			map[string][]any{
+				"alpha": {map[string][]any{"bravo": {1.0}, "charlie": {[]float64{1, 2}}, "delta": {4.0}}},
+				"echo":  {5.0},
+			},
+			[]*parser.SyntaxError{
+				{Offset: 29, Line: 3, Column: 14, Token: "=", Path: "alpha.charlie", Snippet: "charlie={1 2 = 3}", Msg: "unexpected token = in array"},
+			},
+		},
+		{
+			"alpha={bravo=} charlie=3",
+			map[string][]any{"alpha": {map[string][]any{}}, "charlie": {3.0}},
+			[]*parser.SyntaxError{
+				{Offset: 13, Line: 1, Column: 14, Token: "}", Path: "alpha.bravo", Snippet: "alpha={bravo=} charlie=3", Msg: "found }, expected a value"},
+			},
+		},
+		{
+			"alpha={bravo=1 2.5=3 charlie=4} delta=5",
+			map[string][]any{"alpha": {map[string][]any{"bravo": {1.0}, "charlie": {4.0}}}, "delta": {5.0}},
+			[]*parser.SyntaxError{
+				{Offset: 15, Line: 1, Column: 16, Token: "2.5", Path: "alpha", Snippet: "alpha={bravo=1 2.5=3 charlie=4} delta=5", Msg: "found 2.5, expected some kind of key"},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			r := strings.NewReader(tc.in)
+			p := parser.NewParser(r)
+			p.Lenient = true
+			got, err := p.Parse()
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got)
+				assert.Equal(t, tc.diags, p.Diagnostics())
+			}
+		})
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	t.Run("should return syntax error with location in strict mode", func(t *testing.T) {
+		r := strings.NewReader("alpha=1\nbravo={\n  charlie={1 2 = 3}\n}")
+		p := parser.NewParser(r)
+		_, err := p.Parse()
+		var got *parser.SyntaxError
+		if assert.ErrorAs(t, err, &got) {
+			want := &parser.SyntaxError{
+				Offset:  31,
+				Line:    3,
+				Column:  16,
+				Token:   "=",
+				Path:    "bravo.charlie",
+				Snippet: "charlie={1 2 = 3}",
+				Msg:     "unexpected token = in array",
+			}
+			assert.Equal(t, want, got)
+		}
+	})
+	t.Run("should limit snippet to surrounding part of the line", func(t *testing.T) {
+		s := fmt.Sprintf("alpha={%s= %s}", strings.Repeat("1 ", 30), strings.Repeat("2 ", 30))
+		p := parser.NewParser(strings.NewReader(s))
+		_, err := p.Parse()
+		var got *parser.SyntaxError
+		if assert.ErrorAs(t, err, &got) {
+			assert.Equal(t, strings.Repeat("1 ", 20)+"= "+strings.Repeat("2 ", 18)+"2", got.Snippet)
+		}
+	})
+	t.Run("can format error message", func(t *testing.T) {
+		err := &parser.SyntaxError{Line: 3, Column: 16, Path: "bravo", Snippet: "bravo=}", Msg: "found }, expected a value"}
+		assert.Equal(t, `line 3, column 16 at bravo: found }, expected a value near "bravo=}"`, err.Error())
+	})
+}
+
+func TestParserReadErrors(t *testing.T) {
+	errRead := errors.New("read failed")
+	cases := []string{
+		"",
+		"alpha",
+		"alpha=",
+		"alpha=5",
+		"alpha={bravo=1",
+		"alpha={1 2",
+		"alpha={{bravo=1}",
+		"alpha={\"special",
+	}
+	for _, in := range cases {
+		t.Run(fmt.Sprintf("in: %s", in), func(t *testing.T) {
+			r := io.MultiReader(strings.NewReader(in), iotest.ErrReader(errRead))
+			p := parser.NewParser(r)
+			_, err := p.Parse()
+			assert.ErrorIs(t, err, errRead)
+		})
+	}
+	t.Run("should report read errors in lenient mode", func(t *testing.T) {
+		r := io.MultiReader(strings.NewReader("alpha={bravo=1 ="), iotest.ErrReader(errRead))
+		p := parser.NewParser(r)
+		p.Lenient = true
+		_, err := p.Parse()
+		assert.ErrorIs(t, err, errRead)
+	})
+	t.Run("should report unexpected EOF from truncated stream", func(t *testing.T) {
+		r := io.MultiReader(strings.NewReader("alpha={bravo=1"), iotest.ErrReader(io.ErrUnexpectedEOF))
+		p := parser.NewParser(r)
+		_, err := p.Parse()
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+	t.Run("can parse from readers returning single bytes", func(t *testing.T) {
+		in := "alpha={bravo=\"äöü\" charlie={1 2 3}} delta=yes"
+		p := parser.NewParser(iotest.OneByteReader(strings.NewReader(in)))
+		got, err := p.Parse()
+		if assert.NoError(t, err) {
+			want := map[string][]any{
+				"alpha": {map[string][]any{"bravo": {"äöü"}, "charlie": {[]float64{1, 2, 3}}}},
+				"delta": {true},
+			}
+			assert.Equal(t, want, got)
+		}
+	})
+}
+
+func TestParserUnbalanced(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want map[string][]any
+		msg  string
+		path string
+	}{
+		{
+			"missing closing bracket",
+			"alpha={bravo=1",
+			map[string][]any{"alpha": {map[string][]any{"bravo": {1.0}}}},
+			"unexpected end of input, missing closing bracket",
+			"alpha",
+		},
+		{
+			"missing closing brackets in nested object",
+			"alpha={bravo={charlie=1",
+			map[string][]any{"alpha": {map[string][]any{"bravo": {map[string][]any{"charlie": {1.0}}}}}},
+			"unexpected end of input, missing closing bracket",
+			"alpha.bravo",
+		},
+		{
+			"missing closing bracket in array",
+			"alpha={1 2",
+			map[string][]any{"alpha": {[]float64{1, 2}}},
+			"unexpected end of input, missing closing bracket",
+			"alpha",
+		},
+		{
+			"missing closing bracket in empty block",
+			"alpha={",
+			map[string][]any{"alpha": {}},
+			"unexpected end of input, missing closing bracket",
+			"alpha",
+		},
+		{
+			"extra closing bracket",
+			"alpha=1 } bravo=2",
+			map[string][]any{"alpha": {1.0}, "bravo": {2.0}},
+			"unexpected closing bracket",
+			"",
+		},
+	}
+	for _, tc := range cases {
+		t.Run("should return error in strict mode for "+tc.name, func(t *testing.T) {
+			p := parser.NewParser(strings.NewReader(tc.in))
+			_, err := p.Parse()
+			var got *parser.SyntaxError
+			if assert.ErrorAs(t, err, &got) {
+				assert.Equal(t, tc.msg, got.Msg)
+				assert.Equal(t, tc.path, got.Path)
+			}
+		})
+		t.Run("should report diagnostic in lenient mode for "+tc.name, func(t *testing.T) {
+			p := parser.NewParser(strings.NewReader(tc.in))
+			p.Lenient = true
+			got, err := p.Parse()
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got)
+				if assert.Len(t, p.Diagnostics(), 1) {
+					assert.Equal(t, tc.msg, p.Diagnostics()[0].Msg)
+				}
+			}
+		})
+		t.Run("can allow unbalanced brackets for "+tc.name, func(t *testing.T) {
+			p := parser.NewParser(strings.NewReader(tc.in))
+			p.AllowUnbalanced = true
+			got, err := p.Parse()
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParserUnterminatedString(t *testing.T) {
+	in := "alpha=1 bravo=\"trunc"
+	t.Run("should return error in strict mode", func(t *testing.T) {
+		p := parser.NewParser(strings.NewReader(in))
+		_, err := p.Parse()
+		var got *parser.SyntaxError
+		if assert.ErrorAs(t, err, &got) {
+			assert.Equal(t, "unexpected end of input, unterminated string", got.Msg)
+			assert.Equal(t, 15, got.Column)
+		}
+	})
+	t.Run("should report diagnostic in lenient mode", func(t *testing.T) {
+		p := parser.NewParser(strings.NewReader(in))
+		p.Lenient = true
+		got, err := p.Parse()
+		if assert.NoError(t, err) {
+			assert.Equal(t, map[string][]any{"alpha": {1.0}, "bravo": {"trunc"}}, got)
+			if assert.Len(t, p.Diagnostics(), 1) {
+				assert.Equal(t, "unexpected end of input, unterminated string", p.Diagnostics()[0].Msg)
+			}
+		}
+	})
+}
+
+func TestParserEncoding(t *testing.T) {
+	cases := []struct {
+		name     string
+		in       string
+		encoding charset.Encoding
+		want     map[string][]any
+	}{
+		{
+			"UTF-8",
+			"name=\"Kürsk Ünion\" leader=\"François Œuvre\"",
+			charset.Auto,
+			map[string][]any{"name": {"Kürsk Ünion"}, "leader": {"François Œuvre"}},
+		},
+		{
+			"UTF-8 with BOM",
+			"\xEF\xBB\xBFname=\"Kürsk Ünion\"",
+			charset.Auto,
+			map[string][]any{"name": {"Kürsk Ünion"}},
+		},
+		{
+			"Windows-1252",
+			"name=\"K\xFCrsk \xDCnion\" leader=\"Fran\xE7ois \x8Cuvre \x80\"",
+			charset.Auto,
+			map[string][]any{"name": {"Kürsk Ünion"}, "leader": {"François Œuvre €"}},
+		},
+		{
+			"mixed UTF-8 and Windows-1252",
+			"name=\"Kürsk Ünion\" leader=\"Fran\xE7ois\"",
+			charset.Auto,
+			map[string][]any{"name": {"Kürsk Ünion"}, "leader": {"François"}},
+		},
+		{
+			"Windows-1252 in keys and arrays",
+			"n\xE4me={\"\xC5sa\" \"\xD8rn\"}",
+			charset.Auto,
+			map[string][]any{"näme": {[]string{"Åsa", "Ørn"}}},
+		},
+		{
+			"Windows-1252 when forced",
+			"name=\"\xC3\xA9\"",
+			charset.Windows1252,
+			map[string][]any{"name": {"Ã©"}},
+		},
+		{
+			"UTF-8 when forced",
+			"name=\"Fran\xE7ois\"",
+			charset.UTF8,
+			map[string][]any{"name": {"Fran\uFFFDois"}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := parser.NewParser(strings.NewReader(tc.in))
+			p.Encoding = tc.encoding
+			got, err := p.Parse()
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+	t.Run("should report offsets in bytes and snippets in characters", func(t *testing.T) {
+		p := parser.NewParser(strings.NewReader("\xEF\xBB\xBFname=\"K\xFCrsk\" = x"))
+		_, err := p.Parse()
+		var got *parser.SyntaxError
+		if assert.ErrorAs(t, err, &got) {
+			assert.Equal(t, int64(16), got.Offset)
+			assert.Equal(t, 14, got.Column)
+			assert.Equal(t, "name=\"Kürsk\" = x", got.Snippet)
+		}
+	})
+}
@@ -1,5 +1,10 @@
 package parser
 
+import (
+	"fmt"
+	"strconv"
+)
+
 type tokenType string
 
 const (
@@ -10,6 +15,7 @@ const (
 	bracketsClose tokenType = "bracketsClose"
 	identifier    tokenType = "identifier"
 	str           tokenType = "string"
+	unterminated  tokenType = "unterminated" // string at the end of the input without closing quote
 	float         tokenType = "float"
 	integer       tokenType = "integer"
 	boolean       tokenType = "boolean"
@@ -19,3 +25,18 @@ type token struct {
 	typ   tokenType
 	value any
 }
+
+func (t token) String() string {
+	switch t.typ {
+	case endOfFile:
+		return "EOF"
+	case str, unterminated:
+		return strconv.Quote(t.value.(string))
+	case boolean:
+		if t.value.(bool) {
+			return "yes"
+		}
+		return "no"
+	}
+	return fmt.Sprint(t.value)
+}
@@ -0,0 +1,205 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// EmpireSortKeys are the keys for ranking empires.
+var EmpireSortKeys = []string{"score", "military", "economy", "tech", "planets", "pops", "fleet"}
+
+// EmpiresOptions represents the options for the empires report.
+type EmpiresOptions struct {
+	SortBy string // one of [EmpireSortKeys], defaults to "score"
+	All    bool   // include all countries, e.g. pirates, instead of only regular empires
+}
+
+// Empire represents an empire in the empires report.
+type Empire struct {
+	Rank          int
+	ID            int
+	Name          string
+	VictoryScore  float64
+	MilitaryPower float64
+	EconomyPower  float64
+	Income        map[string]float64 // monthly income by resource
+	TechCount     int
+	Planets       int
+	Pops          int
+	FleetSize     int
+	Federation    string
+	Allies        []string
+	AtWarWith     []string
+}
+
+// Research returns the sum of the monthly research income.
+func (e Empire) Research() float64 {
+	return e.Income["physics_research"] + e.Income["society_research"] + e.Income["engineering_research"]
+}
+
+// Empires returns the ranked empires of a gamestate.
+func Empires(gs *stellaris.Gamestate, opt EmpiresOptions) ([]Empire, error) {
+	sortBy := opt.SortBy
+	if sortBy == "" {
+		sortBy = "score"
+	}
+	if !slices.Contains(EmpireSortKeys, sortBy) {
+		return nil, fmt.Errorf("invalid sort key: %s", sortBy)
+	}
+	pops := make(map[int]int) // by owner
+	for _, p := range gs.Pops {
+		if planet, ok := gs.Planets[p.PlanetID]; ok && planet.OwnerID != stellaris.NoID {
+			pops[planet.OwnerID]++
+		}
+	}
+	var empires []Empire
+	for _, c := range gs.Countries {
+		if !opt.All && c.Type != "default" {
+			continue
+		}
+		e := Empire{
+			ID:            c.ID,
+			Name:          c.Name,
+			VictoryScore:  c.VictoryScore,
+			MilitaryPower: c.MilitaryPower,
+			EconomyPower:  c.EconomyPower,
+			Income:        c.Income,
+			TechCount:     c.TechCount,
+			Planets:       len(c.OwnedPlanetIDs),
+			Pops:          pops[c.ID],
+			FleetSize:     c.FleetSize,
+		}
+		if f, ok := gs.Federations[c.FederationID]; ok {
+			e.Federation = f.Name
+		}
+		for _, r := range c.Relations {
+			if r.HasStatus("alliance") {
+				e.Allies = append(e.Allies, countryName(gs, r.CountryID))
+			}
+		}
+		for _, w := range gs.Wars {
+			e.AtWarWith = append(e.AtWarWith, opponents(gs, w, c.ID)...)
+		}
+		slices.Sort(e.Allies)
+		e.Allies = slices.Compact(e.Allies)
+		slices.Sort(e.AtWarWith)
+		e.AtWarWith = slices.Compact(e.AtWarWith)
+		empires = append(empires, e)
+	}
+	key := empireSortKey(sortBy)
+	slices.SortFunc(empires, func(a, b Empire) int {
+		if c := cmp.Compare(key(b), key(a)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	for i := range empires {
+		empires[i].Rank = i + 1
+	}
+	return empires, nil
+}
+
+func empireSortKey(sortBy string) func(e Empire) float64 {
+	switch sortBy {
+	case "military":
+		return func(e Empire) float64 { return e.MilitaryPower }
+	case "economy":
+		return func(e Empire) float64 { return e.EconomyPower }
+	case "tech":
+		return func(e Empire) float64 { return float64(e.TechCount) }
+	case "planets":
+		return func(e Empire) float64 { return float64(e.Planets) }
+	case "pops":
+		return func(e Empire) float64 { return float64(e.Pops) }
+	case "fleet":
+		return func(e Empire) float64 { return float64(e.FleetSize) }
+	}
+	return func(e Empire) float64 { return e.VictoryScore }
+}
+
+// opponents returns the names of the countries on the other side of a war
+// or nothing when the country is not participating.
+func opponents(gs *stellaris.Gamestate, w *stellaris.War, countryID int) []string {
+	isParticipant := func(pp []stellaris.WarParticipant) bool {
+		return slices.ContainsFunc(pp, func(p stellaris.WarParticipant) bool { return p.CountryID == countryID })
+	}
+	var other []stellaris.WarParticipant
+	switch {
+	case isParticipant(w.Attackers):
+		other = w.Defenders
+	case isParticipant(w.Defenders):
+		other = w.Attackers
+	}
+	var names []string
+	for _, p := range other {
+		names = append(names, countryName(gs, p.CountryID))
+	}
+	return names
+}
+
+// countryName returns the name of a country or it's ID when it does not exist.
+func countryName(gs *stellaris.Gamestate, id int) string {
+	if c, ok := gs.Countries[id]; ok {
+		return c.Name
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+// EmpiresTable returns the empires report as table.
+func EmpiresTable(empires []Empire) *Table {
+	t := &Table{
+		Title: "Empires",
+		Columns: []Column{
+			{"Rank", true},
+			{"Empire", false},
+			{"Score", true},
+			{"Military", true},
+			{"Energy", true},
+			{"Minerals", true},
+			{"Food", true},
+			{"Alloys", true},
+			{"Consumer goods", true},
+			{"Research", true},
+			{"Techs", true},
+			{"Planets", true},
+			{"Pops", true},
+			{"Fleet size", true},
+			{"Federation", false},
+			{"Allies", false},
+			{"At war with", false},
+		},
+	}
+	for _, e := range empires {
+		t.Rows = append(t.Rows, []string{
+			strconv.Itoa(e.Rank),
+			e.Name,
+			formatNumber(e.VictoryScore),
+			formatNumber(e.MilitaryPower),
+			formatNumber(e.Income["energy"]),
+			formatNumber(e.Income["minerals"]),
+			formatNumber(e.Income["food"]),
+			formatNumber(e.Income["alloys"]),
+			formatNumber(e.Income["consumer_goods"]),
+			formatNumber(e.Research()),
+			strconv.Itoa(e.TechCount),
+			strconv.Itoa(e.Planets),
+			strconv.Itoa(e.Pops),
+			strconv.Itoa(e.FleetSize),
+			e.Federation,
+			strings.Join(e.Allies, ", "),
+			strings.Join(e.AtWarWith, ", "),
+		})
+	}
+	return t
+}
+
+// formatNumber returns a number rounded to one decimal place.
+func formatNumber(x float64) string {
+	return strconv.FormatFloat(math.Round(x*10)/10, 'f', -1, 64)
+}
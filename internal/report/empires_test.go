@@ -0,0 +1,68 @@
+package report_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/report"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+func TestEmpires(t *testing.T) {
+	gs := stellaris.NewGamestate(testutil.LoadGamestate(t))
+	t.Run("should rank empires by score", func(t *testing.T) {
+		got, err := report.Empires(gs, report.EmpiresOptions{})
+		require.NoError(t, err)
+		require.Len(t, got, 3)
+		e := got[0]
+		assert.Equal(t, 1, e.Rank)
+		assert.Equal(t, "Blooms of Gaea", e.Name)
+		assert.Equal(t, 12500.5, e.MilitaryPower)
+		assert.Equal(t, 120.5, e.Income["energy"])
+		assert.Equal(t, 105.0, e.Research())
+		assert.Equal(t, 4, e.TechCount)
+		assert.Equal(t, 2, e.Planets)
+		assert.Equal(t, 2, e.Pops)
+		assert.Equal(t, 120, e.FleetSize)
+		assert.Equal(t, "Green Alliance", e.Federation)
+		assert.Equal(t, []string{"SPEC_Human_adj Commonwealth"}, e.Allies)
+		assert.Equal(t, []string{"Kel-Azaan Hegemony"}, e.AtWarWith)
+		assert.Equal(t, []string{"Blooms of Gaea", "SPEC_Human_adj Commonwealth"}, got[2].AtWarWith)
+	})
+	t.Run("should rank empires by other keys", func(t *testing.T) {
+		got, err := report.Empires(gs, report.EmpiresOptions{SortBy: "military"})
+		require.NoError(t, err)
+		var names []string
+		for _, e := range got {
+			names = append(names, e.Name)
+		}
+		assert.Equal(t, []string{"Kel-Azaan Hegemony", "Blooms of Gaea", "SPEC_Human_adj Commonwealth"}, names)
+	})
+	t.Run("should only include regular empires by default", func(t *testing.T) {
+		gs := stellaris.NewGamestate(testutil.LoadGamestate(t))
+		gs.Countries[2].Type = "pirate"
+		got, err := report.Empires(gs, report.EmpiresOptions{})
+		require.NoError(t, err)
+		assert.Len(t, got, 2)
+		got, err = report.Empires(gs, report.EmpiresOptions{All: true})
+		require.NoError(t, err)
+		assert.Len(t, got, 3)
+	})
+	t.Run("should return error for invalid sort key", func(t *testing.T) {
+		_, err := report.Empires(gs, report.EmpiresOptions{SortBy: "unknown"})
+		assert.Error(t, err)
+	})
+	t.Run("should create table", func(t *testing.T) {
+		empires, err := report.Empires(gs, report.EmpiresOptions{})
+		require.NoError(t, err)
+		tbl := report.EmpiresTable(empires)
+		require.Len(t, tbl.Rows, 3)
+		assert.Equal(t, []string{
+			"1", "Blooms of Gaea", "2450", "12500.5", "120.5", "90", "30", "25", "15", "105",
+			"4", "2", "2", "120", "Green Alliance", "SPEC_Human_adj Commonwealth", "Kel-Azaan Hegemony",
+		}, tbl.Rows[0])
+	})
+}
@@ -0,0 +1,122 @@
+// Package report creates reports from the typed model of a save game,
+// e.g. a leaderboard of all empires.
+//
+// Reports are tables, which can be rendered as text, Markdown or HTML.
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Formats are the supported output formats of reports.
+var Formats = []string{"text", "markdown", "html"}
+
+// Column represents a column of a table.
+type Column struct {
+	Name    string
+	Numeric bool // numeric columns are right aligned
+}
+
+// Table represents a report as table.
+type Table struct {
+	Title   string
+	Columns []Column
+	Rows    [][]string
+}
+
+// Write writes the table in the format to w.
+func (t *Table) Write(w io.Writer, format string) error {
+	switch format {
+	case "text":
+		return t.WriteText(w)
+	case "markdown":
+		return t.WriteMarkdown(w)
+	case "html":
+		return t.WriteHTML(w)
+	}
+	return fmt.Errorf("invalid format for report: %s", format)
+}
+
+// WriteText writes the table as text with aligned columns to w.
+func (t *Table) WriteText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "%s\n\n", t.Title); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = c.Name
+	}
+	fmt.Fprintln(tw, strings.Join(names, "\t"))
+	for _, row := range t.Rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// WriteMarkdown writes the table as Markdown to w.
+// Tables without a title have no heading.
+func (t *Table) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	if t.Title != "" {
+		fmt.Fprintf(&b, "## %s\n\n", t.Title)
+	}
+	names := make([]string, len(t.Columns))
+	aligns := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = escapeMarkdown(c.Name)
+		if c.Numeric {
+			aligns[i] = "--:"
+		} else {
+			aligns[i] = "--"
+		}
+	}
+	fmt.Fprintf(&b, "| %s |\n", strings.Join(names, " | "))
+	fmt.Fprintf(&b, "| %s |\n", strings.Join(aligns, " | "))
+	for _, row := range t.Rows {
+		cells := make([]string, len(row))
+		for i, s := range row {
+			cells[i] = escapeMarkdown(s)
+		}
+		fmt.Fprintf(&b, "| %s |\n", strings.Join(cells, " | "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapeMarkdown(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+table { border-collapse: collapse; font-family: sans-serif; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+th { background: #eee; }
+.numeric { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+<tr>{{range .Columns}}<th>{{.Name}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr>{{range $i, $s := .}}<td{{if (index $.Columns $i).Numeric}} class="numeric"{{end}}>{{$s}}</td>{{end}}</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the table as HTML document to w.
+func (t *Table) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, t)
+}
@@ -0,0 +1,40 @@
+package report_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/report"
+)
+
+func TestTable(t *testing.T) {
+	tbl := &report.Table{
+		Title:   "Test",
+		Columns: []report.Column{{"Name", false}, {"Value", true}},
+		Rows:    [][]string{{"alpha", "1"}, {"a|b", "22"}},
+	}
+	t.Run("should write text", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, tbl.Write(&buf, "text"))
+		assert.Equal(t, "Test\n\nName   Value\nalpha  1\na|b    22\n", buf.String())
+	})
+	t.Run("should write markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, tbl.Write(&buf, "markdown"))
+		assert.Equal(t, "## Test\n\n| Name | Value |\n| -- | --: |\n| alpha | 1 |\n| a\\|b | 22 |\n", buf.String())
+	})
+	t.Run("should write HTML", func(t *testing.T) {
+		tbl := &report.Table{Title: "<Test>", Columns: []report.Column{{"Name", false}, {"Value", true}}, Rows: [][]string{{"<b>", "1"}}}
+		var buf bytes.Buffer
+		require.NoError(t, tbl.Write(&buf, "html"))
+		assert.Contains(t, buf.String(), "<title>&lt;Test&gt;</title>")
+		assert.Contains(t, buf.String(), `<tr><td>&lt;b&gt;</td><td class="numeric">1</td></tr>`)
+	})
+	t.Run("should return error for invalid format", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Error(t, tbl.Write(&buf, "pdf"))
+	})
+}
@@ -0,0 +1,336 @@
+package report
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// Status of wars.
+const (
+	WarOngoing = "ongoing"
+	WarEnded   = "ended"
+)
+
+// War represents a war in the war report.
+type War struct {
+	ID                    int           `json:"id"`
+	Name                  string        `json:"name"`
+	StartDate             string        `json:"start_date"`
+	Attackers             []Participant `json:"attackers"`
+	Defenders             []Participant `json:"defenders"`
+	AttackerWarGoal       string        `json:"attacker_war_goal"`
+	DefenderWarGoal       string        `json:"defender_war_goal"`
+	AttackerWarExhaustion float64       `json:"attacker_war_exhaustion"` // last known war exhaustion
+	DefenderWarExhaustion float64       `json:"defender_war_exhaustion"` // last known war exhaustion
+	// Exhaustion is the war exhaustion of both sides at the dates of all saves with the war.
+	Exhaustion        []Exhaustion `json:"exhaustion"`
+	Battles           []WarBattle  `json:"battles"`
+	AttackerVictories int          `json:"attacker_victories"` // number of battles won by the attackers
+	DefenderVictories int          `json:"defender_victories"` // number of battles won by the defenders
+	// Status is "ongoing" when the war is in the latest save or "ended" otherwise.
+	// The result of ended wars, e.g. which side won or whether it was a white peace, is unknown,
+	// because the game removes wars from the save when they end.
+	Status string `json:"status"`
+	// LastSeen is the date of the latest save with the war.
+	LastSeen string `json:"last_seen"`
+	// EndedBefore is the date of the first save after the war ended.
+	EndedBefore string `json:"ended_before,omitempty"`
+}
+
+// Participant represents a country participating in a war.
+type Participant struct {
+	CountryID int    `json:"country_id"`
+	Name      string `json:"name"`
+	CallType  string `json:"call_type"` // e.g. "primary" or "alliance"
+}
+
+// Exhaustion represents the war exhaustion of both sides of a war at a date.
+type Exhaustion struct {
+	Date      string  `json:"date"`
+	Attackers float64 `json:"attackers"`
+	Defenders float64 `json:"defenders"`
+}
+
+// WarBattle represents a battle in the war report.
+type WarBattle struct {
+	Date                  string   `json:"date"`
+	Type                  string   `json:"type"`
+	SystemID              int      `json:"system_id"`
+	System                string   `json:"system"`
+	PlanetID              int      `json:"planet_id"`
+	Planet                string   `json:"planet"`
+	Attackers             []string `json:"attackers"`
+	Defenders             []string `json:"defenders"`
+	AttackerVictory       bool     `json:"attacker_victory"`
+	AttackerLosses        float64  `json:"attacker_losses"`
+	DefenderLosses        float64  `json:"defender_losses"`
+	AttackerWarExhaustion float64  `json:"attacker_war_exhaustion"`
+	DefenderWarExhaustion float64  `json:"defender_war_exhaustion"`
+}
+
+// warKey identifies a war across saves.
+// The start date is included, because IDs of ended wars can be reused.
+type warKey struct {
+	id        int
+	startDate string
+}
+
+// snapshot represents the wars of one save.
+type snapshot struct {
+	date string
+	wars map[warKey]War
+}
+
+// WarHistory collects the wars from one or many saves of a campaign, e.g. autosaves.
+//
+// The results of wars are not part of the report, since the save does not record them:
+// The game removes a war from the save when it ends, so only the fact that it ended
+// and the saves between which it ended can be told.
+type WarHistory struct {
+	snapshots []snapshot
+}
+
+// NewWarHistory returns a new war history.
+func NewWarHistory() *WarHistory {
+	return &WarHistory{}
+}
+
+// Add adds the wars of a save.
+func (h *WarHistory) Add(gs *stellaris.Gamestate) {
+	s := snapshot{date: gs.Date, wars: make(map[warKey]War)}
+	for _, w := range gs.Wars {
+		s.wars[warKey{w.ID, w.StartDate}] = newWar(gs, w)
+	}
+	h.snapshots = append(h.snapshots, s)
+}
+
+func newWar(gs *stellaris.Gamestate, w *stellaris.War) War {
+	x := War{
+		ID:                    w.ID,
+		Name:                  w.Name,
+		StartDate:             w.StartDate,
+		Attackers:             participants(gs, w.Attackers),
+		Defenders:             participants(gs, w.Defenders),
+		AttackerWarGoal:       w.AttackerWarGoal,
+		DefenderWarGoal:       w.DefenderWarGoal,
+		AttackerWarExhaustion: w.AttackerWarExhaustion,
+		DefenderWarExhaustion: w.DefenderWarExhaustion,
+		Exhaustion:            []Exhaustion{{gs.Date, w.AttackerWarExhaustion, w.DefenderWarExhaustion}},
+		Battles:               make([]WarBattle, 0),
+		Status:                WarOngoing,
+		LastSeen:              gs.Date,
+	}
+	for _, b := range w.Battles {
+		wb := WarBattle{
+			Date:                  b.Date,
+			Type:                  b.Type,
+			SystemID:              b.SystemID,
+			PlanetID:              b.PlanetID,
+			Attackers:             countryNames(gs, b.AttackerIDs),
+			Defenders:             countryNames(gs, b.DefenderIDs),
+			AttackerVictory:       b.AttackerVictory,
+			AttackerLosses:        b.AttackerLosses,
+			DefenderLosses:        b.DefenderLosses,
+			AttackerWarExhaustion: b.AttackerWarExhaustion,
+			DefenderWarExhaustion: b.DefenderWarExhaustion,
+		}
+		if s, ok := gs.Systems[b.SystemID]; ok {
+			wb.System = s.Name
+		}
+		if p, ok := gs.Planets[b.PlanetID]; ok {
+			wb.Planet = p.Name
+		}
+		x.Battles = append(x.Battles, wb)
+	}
+	return x
+}
+
+func participants(gs *stellaris.Gamestate, pp []stellaris.WarParticipant) []Participant {
+	r := make([]Participant, 0, len(pp))
+	for _, p := range pp {
+		r = append(r, Participant{CountryID: p.CountryID, Name: countryName(gs, p.CountryID), CallType: p.CallType})
+	}
+	return r
+}
+
+func countryNames(gs *stellaris.Gamestate, ids []int) []string {
+	r := make([]string, 0, len(ids))
+	for _, id := range ids {
+		r = append(r, countryName(gs, id))
+	}
+	return r
+}
+
+// Wars returns all wars of the history ordered by their start date.
+//
+// The details of each war are taken from the latest save with the war.
+// The war exhaustion is collected from all saves with the war, once per date,
+// and battles from all saves are combined.
+func (h *WarHistory) Wars() []War {
+	snapshots := slices.Clone(h.snapshots)
+	slices.SortStableFunc(snapshots, func(a, b snapshot) int {
+		return stellaris.CompareDates(a.date, b.date)
+	})
+	wars := make(map[warKey]*War)
+	for i, s := range snapshots {
+		for k, w := range s.wars {
+			previous, ok := wars[k]
+			if ok {
+				exhaustion := slices.Clone(previous.Exhaustion)
+				if n := len(exhaustion); n > 0 && exhaustion[n-1].Date == s.date {
+					exhaustion = exhaustion[:n-1] // saves from the same day, e.g. a manual save and an autosave
+				}
+				w.Exhaustion = append(exhaustion, w.Exhaustion...)
+				w.Battles = mergeBattles(previous.Battles, w.Battles)
+			}
+			wars[k] = &w
+		}
+		for k, w := range wars {
+			if _, ok := s.wars[k]; !ok && w.Status == WarOngoing && i > 0 {
+				w.Status = WarEnded
+				w.EndedBefore = s.date
+			}
+		}
+	}
+	r := make([]War, 0, len(wars))
+	for _, w := range wars {
+		w.AttackerVictories, w.DefenderVictories = 0, 0
+		for _, b := range w.Battles {
+			if b.AttackerVictory {
+				w.AttackerVictories++
+			} else {
+				w.DefenderVictories++
+			}
+		}
+		r = append(r, *w)
+	}
+	slices.SortFunc(r, func(a, b War) int {
+		if c := stellaris.CompareDates(a.StartDate, b.StartDate); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return r
+}
+
+// mergeBattles returns the battles of both lists without duplicates ordered by their date.
+func mergeBattles(a, b []WarBattle) []WarBattle {
+	type key struct {
+		date, typ       string
+		system, planet  int
+		attackerVictory bool
+	}
+	seen := make(map[key]bool)
+	r := make([]WarBattle, 0, len(a)+len(b))
+	for _, x := range slices.Concat(a, b) {
+		k := key{x.Date, x.Type, x.SystemID, x.PlanetID, x.AttackerVictory}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		r = append(r, x)
+	}
+	slices.SortStableFunc(r, func(a, b WarBattle) int {
+		return stellaris.CompareDates(a.Date, b.Date)
+	})
+	return r
+}
+
+// WriteWarsJSON writes the war report as JSON to w.
+func WriteWarsJSON(w io.Writer, wars []War) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(map[string]any{"wars": wars})
+}
+
+// WriteWarsMarkdown writes the war report as Markdown to w, e.g. for a campaign chronicle.
+func WriteWarsMarkdown(w io.Writer, wars []War) error {
+	var b strings.Builder
+	b.WriteString("# Wars\n")
+	if len(wars) == 0 {
+		b.WriteString("\nNo wars found.\n")
+	}
+	for _, x := range wars {
+		fmt.Fprintf(&b, "\n## %s\n\n", x.Name)
+		fmt.Fprintf(&b, "- Started: %s\n", x.StartDate)
+		if x.Status == WarEnded {
+			fmt.Fprintf(&b, "- Status: ended between %s and %s, result unknown\n", x.LastSeen, x.EndedBefore)
+		} else {
+			fmt.Fprintf(&b, "- Status: ongoing\n")
+		}
+		fmt.Fprintf(&b, "- Attackers: %s\n", formatParticipants(x.Attackers))
+		fmt.Fprintf(&b, "- Defenders: %s\n", formatParticipants(x.Defenders))
+		fmt.Fprintf(&b, "- War goals: %s (attackers), %s (defenders)\n", x.AttackerWarGoal, x.DefenderWarGoal)
+		fmt.Fprintf(&b, "- War exhaustion: %s (attackers), %s (defenders)\n", formatExhaustion(x.AttackerWarExhaustion), formatExhaustion(x.DefenderWarExhaustion))
+		fmt.Fprintf(&b, "- Battles won: %d (attackers), %d (defenders)\n", x.AttackerVictories, x.DefenderVictories)
+		if len(x.Exhaustion) > 1 {
+			b.WriteString("\n### War exhaustion\n\n")
+			t := &Table{Columns: []Column{{"Date", false}, {"Attackers", true}, {"Defenders", true}}}
+			for _, e := range x.Exhaustion {
+				t.Rows = append(t.Rows, []string{e.Date, formatExhaustion(e.Attackers), formatExhaustion(e.Defenders)})
+			}
+			if err := t.WriteMarkdown(&b); err != nil {
+				return err
+			}
+		}
+		if len(x.Battles) > 0 {
+			b.WriteString("\n### Battles\n\n")
+			t := &Table{Columns: []Column{
+				{"Date", false},
+				{"Type", false},
+				{"Location", false},
+				{"Attackers", false},
+				{"Defenders", false},
+				{"Winner", false},
+				{"Attacker losses", true},
+				{"Defender losses", true},
+			}}
+			for _, battle := range x.Battles {
+				location := battle.System
+				if battle.Planet != "" {
+					location = battle.Planet + ", " + location
+				}
+				winner := "defenders"
+				if battle.AttackerVictory {
+					winner = "attackers"
+				}
+				t.Rows = append(t.Rows, []string{
+					battle.Date,
+					battle.Type,
+					location,
+					strings.Join(battle.Attackers, ", "),
+					strings.Join(battle.Defenders, ", "),
+					winner,
+					formatNumber(battle.AttackerLosses),
+					formatNumber(battle.DefenderLosses),
+				})
+			}
+			if err := t.WriteMarkdown(&b); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatParticipants(pp []Participant) string {
+	var ss []string
+	for _, p := range pp {
+		ss = append(ss, fmt.Sprintf("%s (%s)", p.Name, p.CallType))
+	}
+	return strings.Join(ss, ", ")
+}
+
+// formatExhaustion returns a war exhaustion rounded to two decimal places.
+func formatExhaustion(x float64) string {
+	return strconv.FormatFloat(math.Round(x*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,120 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/report"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+func TestWarHistory(t *testing.T) {
+	t.Run("should create wars from one save", func(t *testing.T) {
+		h := report.NewWarHistory()
+		h.Add(stellaris.NewGamestate(testutil.LoadGamestate(t)))
+		got := h.Wars()
+		require.Len(t, got, 1)
+		w := got[0]
+		assert.Equal(t, "Gaean-Kel War", w.Name)
+		assert.Equal(t, "2400.01.01", w.StartDate)
+		assert.Equal(t, []report.Participant{
+			{CountryID: 0, Name: "Blooms of Gaea", CallType: "primary"},
+			{CountryID: 1, Name: "SPEC_Human_adj Commonwealth", CallType: "alliance"},
+		}, w.Attackers)
+		assert.Equal(t, []report.Participant{{CountryID: 2, Name: "Kel-Azaan Hegemony", CallType: "primary"}}, w.Defenders)
+		assert.Equal(t, "wg_humiliation", w.AttackerWarGoal)
+		assert.Equal(t, "wg_subjugation", w.DefenderWarGoal)
+		assert.Equal(t, []report.Exhaustion{{Date: "2415.06.06", Attackers: 0.25, Defenders: 0.6}}, w.Exhaustion)
+		assert.Equal(t, report.WarOngoing, w.Status)
+		assert.Equal(t, 1, w.AttackerVictories)
+		assert.Equal(t, 1, w.DefenderVictories)
+		require.Len(t, w.Battles, 2)
+		assert.Equal(t, report.WarBattle{
+			Date:                  "2405.02.10",
+			Type:                  "armies",
+			SystemID:              0,
+			System:                "Gaea",
+			PlanetID:              4,
+			Planet:                "Gaea II",
+			Attackers:             []string{"Kel-Azaan Hegemony"},
+			Defenders:             []string{"Blooms of Gaea"},
+			AttackerVictory:       false,
+			AttackerLosses:        3,
+			DefenderLosses:        1,
+			AttackerWarExhaustion: 1.5,
+			DefenderWarExhaustion: 0.5,
+		}, w.Battles[1])
+	})
+	t.Run("should combine wars from many saves", func(t *testing.T) {
+		later := stellaris.NewGamestate(testutil.LoadGamestate(t))
+		earlier := stellaris.NewGamestate(testutil.LoadGamestate(t))
+		earlier.Date = "2402.01.01"
+		earlier.Wars[0].AttackerWarExhaustion = 0.1
+		earlier.Wars[0].Battles = earlier.Wars[0].Battles[:1]
+		ended := stellaris.NewGamestate(testutil.LoadGamestate(t))
+		ended.Date = "2420.01.01"
+		ended.Wars = map[int]*stellaris.War{}
+		h := report.NewWarHistory()
+		h.Add(ended)
+		h.Add(later)
+		h.Add(earlier)
+		got := h.Wars()
+		require.Len(t, got, 1)
+		w := got[0]
+		assert.Equal(t, []report.Exhaustion{
+			{Date: "2402.01.01", Attackers: 0.1, Defenders: 0.6},
+			{Date: "2415.06.06", Attackers: 0.25, Defenders: 0.6},
+		}, w.Exhaustion)
+		assert.Len(t, w.Battles, 2)
+		assert.Equal(t, 0.25, w.AttackerWarExhaustion)
+		assert.Equal(t, report.WarEnded, w.Status)
+		assert.Equal(t, "2415.06.06", w.LastSeen)
+		assert.Equal(t, "2420.01.01", w.EndedBefore)
+	})
+	t.Run("should collect war exhaustion once per date", func(t *testing.T) {
+		h := report.NewWarHistory()
+		h.Add(stellaris.NewGamestate(testutil.LoadGamestate(t)))
+		h.Add(stellaris.NewGamestate(testutil.LoadGamestate(t)))
+		got := h.Wars()
+		require.Len(t, got, 1)
+		assert.Equal(t, []report.Exhaustion{{Date: "2415.06.06", Attackers: 0.25, Defenders: 0.6}}, got[0].Exhaustion)
+		assert.Len(t, got[0].Battles, 2)
+	})
+}
+
+func TestWriteWars(t *testing.T) {
+	h := report.NewWarHistory()
+	h.Add(stellaris.NewGamestate(testutil.LoadGamestate(t)))
+	wars := h.Wars()
+	t.Run("should write JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteWarsJSON(&buf, wars))
+		assert.Contains(t, buf.String(), "\n    \"wars\"")
+		var got map[string][]map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		require.Len(t, got["wars"], 1)
+		assert.Equal(t, "Gaean-Kel War", got["wars"][0]["name"])
+		assert.Equal(t, "ongoing", got["wars"][0]["status"])
+		assert.NotContains(t, got["wars"][0], "ended_before")
+	})
+	t.Run("should write Markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteWarsMarkdown(&buf, wars))
+		s := buf.String()
+		assert.Contains(t, s, "## Gaean-Kel War\n")
+		assert.Contains(t, s, "- Attackers: Blooms of Gaea (primary), SPEC_Human_adj Commonwealth (alliance)\n")
+		assert.Contains(t, s, "- War exhaustion: 0.25 (attackers), 0.6 (defenders)\n")
+		assert.Contains(t, s, "| 2405.02.10 | armies | Gaea II, Gaea | Kel-Azaan Hegemony | Blooms of Gaea | defenders | 3 | 1 |\n")
+		assert.NotContains(t, s, "### War exhaustion", "only shown for many saves")
+	})
+	t.Run("should write Markdown without wars", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteWarsMarkdown(&buf, nil))
+		assert.Equal(t, "# Wars\n\nNo wars found.\n", buf.String())
+	})
+}
@@ -0,0 +1,34 @@
+package schema
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+)
+
+// Schemas for the gamestate of known game versions, named by major and minor version.
+//
+//go:embed bundled/*.json
+var bundled embed.FS
+
+var versionPattern = regexp.MustCompile(`v?(\d+)\.(\d+)`)
+
+// Bundled returns the bundled schema for the gamestate of a game version,
+// e.g. "Andromeda v3.12.5" from the meta file.
+// Schemas are bundled per minor version of the game.
+func Bundled(version string) (*Schema, error) {
+	m := versionPattern.FindStringSubmatch(version)
+	if m == nil {
+		return nil, fmt.Errorf("invalid game version: %q", version)
+	}
+	f, err := bundled.Open(fmt.Sprintf("bundled/%s.%s.json", m[1], m[2]))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no bundled schema for game version %s", version)
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
@@ -0,0 +1,253 @@
+// Package schema infers JSON Schemas from parsed save files.
+//
+// The schemas describe the JSON output of save files:
+// Every key of an object has an array with all values for that key,
+// because keys can be repeated in save files.
+//
+// Observations which are not constraints are stored as annotations with a "x-" prefix,
+// so that schemas inferred from one save can be used for validating other saves:
+//   - x-cardinality: whether a key was always "single" or sometimes "repeated"
+//   - x-id-map: whether an object is a collection of entities keyed by ID
+//   - x-minimum and x-maximum: the range of observed numbers
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+)
+
+// Draft is the URI of the JSON Schema version of the schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// IDPattern is the pattern for the keys of ID maps.
+const IDPattern = "^[0-9]+$"
+
+// maxExamples is the maximum number of examples collected for a value.
+const maxExamples = 3
+
+// Types represents the JSON types of a value.
+// It is encoded as string when it has only one type.
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return json.Unmarshal(data, (*[]string)(t))
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*t = Types{s}
+	return nil
+}
+
+// Schema represents a JSON Schema.
+// Only the keywords needed for describing save files are supported.
+type Schema struct {
+	// Bool is set for the boolean schemas true and false, which allow or reject all values.
+	Bool *bool `json:"-"`
+
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 Types              `json:"type,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	PatternProperties    map[string]*Schema `json:"patternProperties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Examples             []any              `json:"examples,omitempty"`
+	GameVersion          string             `json:"x-game-version,omitempty"`
+	Cardinality          string             `json:"x-cardinality,omitempty"`
+	IDMap                bool               `json:"x-id-map,omitempty"`
+	ObservedMinimum      *float64           `json:"x-minimum,omitempty"`
+	ObservedMaximum      *float64           `json:"x-maximum,omitempty"`
+}
+
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if s.Bool != nil {
+		return json.Marshal(*s.Bool)
+	}
+	type schema Schema
+	return json.Marshal((*schema)(s))
+}
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*s = Schema{Bool: &b}
+		return nil
+	}
+	type schema Schema
+	return json.Unmarshal(data, (*schema)(s))
+}
+
+// Infer returns a schema for data, which was inferred from all of it's values.
+func Infer(data map[string][]any) *Schema {
+	n := newNode()
+	n.add(data)
+	s := n.schema()
+	s.Schema = Draft
+	return s
+}
+
+// node collects the observations for a value at a path.
+type node struct {
+	types    map[string]bool
+	objects  int                  // number of observed regular objects
+	props    map[string]*property // properties of regular objects
+	entities *property            // entities of ID maps
+	items    *node                // elements of arrays
+	hasRange bool
+	min, max float64
+	examples []any
+}
+
+// property collects the observations for a key of an object.
+type property struct {
+	seen     int // number of objects with this key
+	repeated bool
+	value    *node
+}
+
+func newNode() *node {
+	return &node{types: make(map[string]bool), props: make(map[string]*property)}
+}
+
+func (n *node) add(v any) {
+	switch x := v.(type) {
+	case nil:
+		n.types["null"] = true
+	case string:
+		n.types["string"] = true
+		n.addExample(x)
+	case bool:
+		n.types["boolean"] = true
+		n.addExample(x)
+	case float64:
+		n.types["number"] = true
+		n.addExample(x)
+		if !n.hasRange || x < n.min {
+			n.min = x
+		}
+		if !n.hasRange || x > n.max {
+			n.max = x
+		}
+		n.hasRange = true
+	case map[string][]any:
+		n.types["object"] = true
+		n.addObject(x)
+	case []float64:
+		addArray(n, x)
+	case []string:
+		addArray(n, x)
+	case []bool:
+		addArray(n, x)
+	case []map[string][]any:
+		addArray(n, x)
+	case []any:
+		addArray(n, x)
+	}
+}
+
+func addArray[T any](n *node, elements []T) {
+	n.types["array"] = true
+	if n.items == nil {
+		n.items = newNode()
+	}
+	for _, e := range elements {
+		n.items.add(e)
+	}
+}
+
+func (n *node) addObject(m map[string][]any) {
+	if tree.IsIDMap(m) {
+		if n.entities == nil {
+			n.entities = &property{value: newNode()}
+		}
+		for _, id := range tree.IDs(m) {
+			n.entities.addValues(m[id])
+		}
+		return
+	}
+	n.objects++
+	for _, k := range tree.Keys(m) {
+		p, ok := n.props[k]
+		if !ok {
+			p = &property{value: newNode()}
+			n.props[k] = p
+		}
+		p.seen++
+		p.addValues(m[k])
+	}
+}
+
+func (p *property) addValues(vv []any) {
+	if len(vv) > 1 {
+		p.repeated = true
+	}
+	for _, v := range vv {
+		p.value.add(v)
+	}
+}
+
+func (n *node) addExample(v any) {
+	if len(n.examples) < maxExamples && !slices.Contains(n.examples, v) {
+		n.examples = append(n.examples, v)
+	}
+}
+
+// schema returns the schema for the observations of n.
+func (n *node) schema() *Schema {
+	s := &Schema{Examples: n.examples}
+	for _, t := range []string{"array", "boolean", "null", "number", "object", "string"} {
+		if n.types[t] {
+			s.Type = append(s.Type, t)
+		}
+	}
+	if n.hasRange {
+		s.ObservedMinimum = &n.min
+		s.ObservedMaximum = &n.max
+	}
+	if n.items != nil {
+		s.Items = n.items.schema()
+	}
+	if n.entities != nil {
+		s.IDMap = true
+		s.PatternProperties = map[string]*Schema{IDPattern: n.entities.schema()}
+	}
+	if len(n.props) > 0 {
+		s.Properties = make(map[string]*Schema)
+		for k, p := range n.props {
+			s.Properties[k] = p.schema()
+			if p.seen == n.objects && n.entities == nil {
+				s.Required = append(s.Required, k)
+			}
+		}
+		slices.Sort(s.Required)
+	}
+	return s
+}
+
+// schema returns the schema for the array of values of a key.
+func (p *property) schema() *Schema {
+	s := &Schema{Type: Types{"array"}, Items: p.value.schema(), Cardinality: "single"}
+	if p.repeated {
+		s.Cardinality = "repeated"
+	}
+	return s
+}
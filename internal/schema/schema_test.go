@@ -0,0 +1,97 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/parser"
+	"github.com/ErikKalkoken/stellaris-tool/internal/schema"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+)
+
+func TestInfer(t *testing.T) {
+	data := parse(t, `
+		name="Alpha"
+		version=3
+		flag=yes
+		color={ 1 2 3 }
+		tag="a"
+		tag="b"
+		country={
+			0={ name="X" power=10 capital=1 }
+			1={ name="Y" power=25.5 }
+			2=none
+		}
+		mixed={ 1 "a" }
+	`)
+	s := schema.Infer(data)
+	t.Run("should describe root object", func(t *testing.T) {
+		assert.Equal(t, schema.Draft, s.Schema)
+		assert.Equal(t, schema.Types{"object"}, s.Type)
+		assert.Equal(t, []string{"color", "country", "flag", "mixed", "name", "tag", "version"}, s.Required)
+	})
+	t.Run("should describe values of keys as arrays", func(t *testing.T) {
+		p := s.Properties["name"]
+		assert.Equal(t, schema.Types{"array"}, p.Type)
+		assert.Equal(t, schema.Types{"string"}, p.Items.Type)
+		assert.Equal(t, []any{"Alpha"}, p.Items.Examples)
+		assert.Equal(t, schema.Types{"boolean"}, s.Properties["flag"].Items.Type)
+		assert.Equal(t, schema.Types{"number"}, s.Properties["color"].Items.Items.Type)
+		assert.Equal(t, schema.Types{"number", "string"}, s.Properties["mixed"].Items.Items.Type)
+	})
+	t.Run("should report cardinality", func(t *testing.T) {
+		assert.Equal(t, "single", s.Properties["name"].Cardinality)
+		assert.Equal(t, "repeated", s.Properties["tag"].Cardinality)
+		assert.Equal(t, []any{"a", "b"}, s.Properties["tag"].Items.Examples)
+	})
+	t.Run("should describe ID maps", func(t *testing.T) {
+		c := s.Properties["country"].Items
+		assert.True(t, c.IDMap)
+		assert.Nil(t, c.Properties)
+		e := c.PatternProperties[schema.IDPattern]
+		require.NotNil(t, e)
+		assert.Equal(t, schema.Types{"null", "object"}, e.Items.Type)
+		assert.Equal(t, []string{"name", "power"}, e.Items.Required)
+		assert.Contains(t, e.Items.Properties, "capital")
+	})
+	t.Run("should report range of numbers", func(t *testing.T) {
+		p := s.Properties["country"].Items.PatternProperties[schema.IDPattern].Items.Properties["power"].Items
+		assert.Equal(t, 10.0, *p.ObservedMinimum)
+		assert.Equal(t, 25.5, *p.ObservedMaximum)
+	})
+}
+
+func TestSchemaJSON(t *testing.T) {
+	s := schema.Infer(parse(t, `a=1 b={ x="y" } b={ x=none }`))
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "object", got["type"])
+	b := got["properties"].(map[string]any)["b"].(map[string]any)
+	assert.Equal(t, "repeated", b["x-cardinality"])
+	x := b["items"].(map[string]any)["properties"].(map[string]any)["x"].(map[string]any)
+	assert.Equal(t, []any{"null", "string"}, x["items"].(map[string]any)["type"])
+	var s2 schema.Schema
+	require.NoError(t, json.Unmarshal(data, &s2))
+	assert.Equal(t, s, &s2)
+}
+
+func TestInferGamestate(t *testing.T) {
+	s := schema.Infer(testutil.LoadGamestate(t))
+	planets := s.Properties["planets"].Items.Properties["planet"].Items
+	assert.True(t, planets.IDMap)
+	planet := planets.PatternProperties[schema.IDPattern].Items
+	assert.Contains(t, planet.Required, "planet_class")
+	assert.Equal(t, schema.Types{"string"}, planet.Properties["planet_class"].Items.Type)
+}
+
+func parse(t *testing.T, s string) map[string][]any {
+	data, err := parser.NewParser(strings.NewReader(s)).Parse()
+	require.NoError(t, err)
+	return data
+}
@@ -0,0 +1,238 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Violation represents a value which does not match it's schema.
+type Violation struct {
+	Path string // key path of the value, e.g. "country.0.name"
+	Msg  string
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Msg
+	}
+	return v.Path + ": " + v.Msg
+}
+
+// Load reads a schema in JSON from r.
+func Load(r io.Reader) (*Schema, error) {
+	var s Schema
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &s, nil
+}
+
+// Validate checks data against the schema and returns all violations.
+//
+// The following keywords are validated:
+// type, enum, minimum, maximum, properties, patternProperties, additionalProperties,
+// required, items, minItems and maxItems. All other keywords are ignored.
+//
+// Paths of values are dotted keys. When a key has more than one value
+// or for elements of arrays the index is added in brackets, e.g. "tag[1]".
+func (s *Schema) Validate(data map[string][]any) []Violation {
+	v := &validator{patterns: make(map[string]*regexp.Regexp)}
+	v.validate(s, "", data)
+	return v.violations
+}
+
+type validator struct {
+	patterns   map[string]*regexp.Regexp
+	violations []Violation
+}
+
+func (v *validator) report(path, format string, a ...any) {
+	v.violations = append(v.violations, Violation{Path: path, Msg: fmt.Sprintf(format, a...)})
+}
+
+func (v *validator) validate(s *Schema, path string, x any) {
+	if s == nil {
+		return
+	}
+	if s.Bool != nil {
+		if !*s.Bool {
+			v.report(path, "not allowed")
+		}
+		return
+	}
+	t := typeOf(x)
+	if len(s.Type) > 0 && !slices.Contains(s.Type, t) {
+		v.report(path, "expected %s, found %s", strings.Join(s.Type, " or "), t)
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, x) {
+		v.report(path, "value %v is not one of %v", x, s.Enum)
+	}
+	switch y := x.(type) {
+	case float64:
+		if s.Minimum != nil && y < *s.Minimum {
+			v.report(path, "number %v is less than minimum %v", y, *s.Minimum)
+		}
+		if s.Maximum != nil && y > *s.Maximum {
+			v.report(path, "number %v is greater than maximum %v", y, *s.Maximum)
+		}
+	case map[string][]any:
+		v.validateObject(s, path, y)
+	default:
+		if t == "array" {
+			v.validateArray(s, path, elements(x))
+		}
+	}
+}
+
+func (v *validator) validateObject(s *Schema, path string, m map[string][]any) {
+	for _, k := range s.Required {
+		if _, ok := m[k]; !ok {
+			v.report(path, "missing required key %q", k)
+		}
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		var schemas []*Schema
+		if x, ok := s.Properties[k]; ok {
+			schemas = append(schemas, x)
+		}
+		for pattern, x := range s.PatternProperties {
+			re, err := v.compile(pattern)
+			if err != nil {
+				v.report(path, "invalid pattern %q: %s", pattern, err)
+				continue
+			}
+			if re.MatchString(k) {
+				schemas = append(schemas, x)
+			}
+		}
+		if len(schemas) == 0 && s.AdditionalProperties != nil {
+			schemas = append(schemas, s.AdditionalProperties)
+		}
+		for _, x := range schemas {
+			v.validateValues(x, p, m[k])
+		}
+	}
+}
+
+// validateValues validates the values of a key, which are described by an array schema.
+func (v *validator) validateValues(s *Schema, path string, vv []any) {
+	if s.Bool != nil || len(s.Type) > 0 && !slices.Contains(s.Type, "array") {
+		for i, x := range vv {
+			v.validate(s, indexPath(path, i, len(vv)), x)
+		}
+		return
+	}
+	v.validateArray(s, path, vv)
+}
+
+func (v *validator) validateArray(s *Schema, path string, elements []any) {
+	if s.MinItems != nil && len(elements) < *s.MinItems {
+		v.report(path, "expected at least %d values, found %d", *s.MinItems, len(elements))
+	}
+	if s.MaxItems != nil && len(elements) > *s.MaxItems {
+		v.report(path, "expected at most %d values, found %d", *s.MaxItems, len(elements))
+	}
+	for i, x := range elements {
+		v.validate(s.Items, indexPath(path, i, len(elements)), x)
+	}
+}
+
+func (v *validator) compile(pattern string) (*regexp.Regexp, error) {
+	if re, ok := v.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	v.patterns[pattern] = re
+	return re, nil
+}
+
+// indexPath returns the path for the element at index i of n elements.
+// The index is omitted for a single element.
+func indexPath(path string, i, n int) string {
+	if n == 1 {
+		return path
+	}
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// typeOf returns the JSON type of a value.
+func typeOf(x any) string {
+	switch x.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case map[string][]any:
+		return "object"
+	case []float64, []string, []bool, []map[string][]any, []any:
+		return "array"
+	}
+	return fmt.Sprintf("%T", x)
+}
+
+// elements returns the elements of an array.
+func elements(x any) []any {
+	switch y := x.(type) {
+	case []any:
+		return y
+	case []float64:
+		return toAny(y)
+	case []string:
+		return toAny(y)
+	case []bool:
+		return toAny(y)
+	case []map[string][]any:
+		return toAny(y)
+	}
+	return nil
+}
+
+func toAny[T any](s []T) []any {
+	r := make([]any, len(s))
+	for i, x := range s {
+		r[i] = x
+	}
+	return r
+}
+
+// inEnum reports whether x is one of the values of enum.
+// Arrays and objects are compared in their JSON representation, e.g. []float64{1, 2} as []any{1, 2}.
+func inEnum(enum []any, x any) bool {
+	switch x.(type) {
+	case nil, bool, float64, string:
+	default:
+		data, err := json.Marshal(x)
+		if err != nil {
+			return false
+		}
+		if err := json.Unmarshal(data, &x); err != nil {
+			return false
+		}
+	}
+	return slices.ContainsFunc(enum, func(v any) bool {
+		return reflect.DeepEqual(v, x)
+	})
+}
@@ -0,0 +1,120 @@
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/schema"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+)
+
+func TestValidate(t *testing.T) {
+	s, err := schema.Load(strings.NewReader(`{
+		"type": "object",
+		"required": ["name", "country"],
+		"properties": {
+			"name": {"type": "array", "maxItems": 1, "items": {"type": "string"}},
+			"size": {"type": "number", "minimum": 1, "maximum": 10},
+			"type": {"type": "array", "items": {"enum": ["a", "b"]}},
+			"pair": {"type": "array", "items": {"enum": [[1, 2], {"a": ["b"]}]}},
+			"color": {"type": "array", "items": {"type": "array", "items": {"type": "number"}}},
+			"country": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"patternProperties": {
+						"^[0-9]+$": {"type": "array", "items": {"type": ["null", "object"], "required": ["capital"]}}
+					},
+					"additionalProperties": false
+				}
+			}
+		}
+	}`))
+	require.NoError(t, err)
+	cases := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			"valid",
+			`name="x" size=5 type=a pair={ 1 2 } pair={ a=b } color={ 1 2 } country={ 0={ capital=1 } 1=none }`,
+			nil,
+		},
+		{
+			"missing required key",
+			`name="x"`,
+			[]string{`missing required key "country"`},
+		},
+		{
+			"wrong type",
+			`name=1 country=2`,
+			[]string{"country: expected object, found number", "name: expected string, found number"},
+		},
+		{
+			"too many values",
+			`name="x" name="y" country={ 0={ capital=1 } }`,
+			[]string{"name: expected at most 1 values, found 2"},
+		},
+		{
+			"number out of range",
+			`name="x" size=0 size=11 country={ 0={ capital=1 } }`,
+			[]string{"size[0]: number 0 is less than minimum 1", "size[1]: number 11 is greater than maximum 10"},
+		},
+		{
+			"value not in enum",
+			`name="x" type=c country={ 0={ capital=1 } }`,
+			[]string{"type: value c is not one of [a b]"},
+		},
+		{
+			"array or object not in enum",
+			`name="x" pair={ 1 3 } pair={ a=c } country={ 0={ capital=1 } }`,
+			[]string{"pair[0]: value [1 3] is not one of [[1 2] map[a:[b]]]", "pair[1]: value map[a:[c]] is not one of [[1 2] map[a:[b]]]"},
+		},
+		{
+			"wrong element type",
+			`name="x" color={ 1 "a" } country={ 0={ capital=1 } }`,
+			[]string{"color[1]: expected number, found string"},
+		},
+		{
+			"nested violations",
+			`name="x" country={ 0={ capital=1 } 1={ name="y" } alpha=3 }`,
+			[]string{`country.1: missing required key "capital"`, "country.alpha: not allowed"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, v := range s.Validate(parse(t, tc.input)) {
+				got = append(got, v.String())
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestValidateInferred(t *testing.T) {
+	data := testutil.LoadGamestate(t)
+	s := schema.Infer(data)
+	assert.Empty(t, s.Validate(data))
+	delete(data, "country")
+	assert.NotEmpty(t, s.Validate(data))
+}
+
+func TestBundled(t *testing.T) {
+	t.Run("should return schema for game version", func(t *testing.T) {
+		s, err := schema.Bundled("Andromeda v3.12.5")
+		require.NoError(t, err)
+		assert.Equal(t, "3.12", s.GameVersion)
+		assert.Empty(t, s.Validate(testutil.LoadGamestate(t)))
+	})
+	t.Run("should return error for unknown version", func(t *testing.T) {
+		_, err := schema.Bundled("Orion v1.0.0")
+		assert.Error(t, err)
+		_, err = schema.Bundled("unknown")
+		assert.Error(t, err)
+	})
+}
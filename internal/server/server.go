@@ -0,0 +1,247 @@
+// Package server provides a REST API for the data of a parsed save game.
+//
+// The API has the following endpoints, which all return JSON:
+//
+//	GET /                   list of endpoints
+//	GET /meta               meta file of the save
+//	GET /{collection}       entities of a collection, e.g. /countries
+//	GET /{collection}/{id}  entity of a collection, e.g. /countries/0
+//	GET /query?path=...     value at a dotted path in the gamestate, e.g. /query?path=galaxy.shape
+//
+// Collections can be filtered by the values of their entities with query parameters,
+// e.g. /planets?owner=0 or /fleets?combat.coordinate.origin=3.
+// A filter matches when any value at it's path matches, including the values of repeated keys
+// and the elements of arrays, e.g. /countries?owned_planets=4.
+// The parameters limit and offset allow paging through large collections.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/table"
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// entity represents an entity of a collection in responses.
+type entity struct {
+	ID    string `json:"id"`
+	Value any    `json:"value"`
+}
+
+// Server is a HTTP handler, which serves the data of a save game.
+type Server struct {
+	// AllowOrigin is the value of the Access-Control-Allow-Origin header of all responses,
+	// e.g. "*" or "http://localhost:3000". This allows web apps on other origins to use the API.
+	// The header is omitted when empty.
+	AllowOrigin string
+
+	meta      map[string][]any
+	gamestate map[string][]any
+	mux       *http.ServeMux
+}
+
+// New returns a new server for the data of a save game.
+func New(meta, gamestate map[string][]any) *Server {
+	s := &Server{meta: meta, gamestate: gamestate, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /{$}", s.handleIndex)
+	s.mux.HandleFunc("GET /meta", s.handleMeta)
+	s.mux.HandleFunc("GET /query", s.handleQuery)
+	s.mux.HandleFunc("GET /{collection}", s.handleCollection)
+	s.mux.HandleFunc("GET /{collection}/{id}", s.handleEntity)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.AllowOrigin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", s.AllowOrigin)
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	endpoints := []string{"/meta", "/query?path={path}"}
+	for _, c := range stellaris.Collections {
+		endpoints = append(endpoints, "/"+c.Name, "/"+c.Name+"/{id}")
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"endpoints": endpoints})
+}
+
+func (s *Server) handleMeta(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.meta)
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		writeError(w, http.StatusBadRequest, "missing parameter: path")
+		return
+	}
+	v, ok := tree.Get(s.gamestate, path)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("path not found: %s", path))
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (s *Server) handleCollection(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.collection(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	limit, err := intParam(q.Get("limit"), -1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid parameter: limit")
+		return
+	}
+	offset, err := intParam(q.Get("offset"), 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid parameter: offset")
+		return
+	}
+	q.Del("limit")
+	q.Del("offset")
+	entities := make([]entity, 0)
+	var n int
+	for _, id := range tree.IDs(m) {
+		o, ok := tree.Object(m, id)
+		if !ok {
+			continue // entity was deleted
+		}
+		if !matches(o, q) {
+			continue
+		}
+		n++
+		if n <= offset {
+			continue
+		}
+		if limit >= 0 && len(entities) == limit {
+			break
+		}
+		entities = append(entities, entity{ID: id, Value: o})
+	}
+	writeJSON(w, http.StatusOK, entities)
+}
+
+func (s *Server) handleEntity(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.collection(w, r)
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+	if !tree.IsID(id) { // other IDs would be paths within entities, e.g. "0.name"
+		writeError(w, http.StatusNotFound, fmt.Sprintf("entity not found: %s", id))
+		return
+	}
+	o, ok := tree.Object(m, id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("entity not found: %s", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, o)
+}
+
+// collection returns the collection of the request or writes an error response.
+func (s *Server) collection(w http.ResponseWriter, r *http.Request) (map[string][]any, bool) {
+	name := r.PathValue("collection")
+	c, ok := stellaris.FindCollection(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown collection: %s", name))
+		return nil, false
+	}
+	m, ok := tree.Object(s.gamestate, c.Section)
+	if !ok {
+		m = make(map[string][]any) // empty collections have no values
+	}
+	return m, true
+}
+
+// matches reports whether any of the values at the dotted path of each filter in o match.
+func matches(o map[string][]any, filters map[string][]string) bool {
+	for path, want := range filters {
+		found := slices.ContainsFunc(values(o, strings.Split(path, ".")), func(v any) bool {
+			return slices.Contains(want, table.Format(v))
+		})
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// values returns all scalar values at the path of keys in v,
+// including the values of repeated keys and the elements of arrays.
+func values(v any, keys []string) []any {
+	var r []any
+	switch x := v.(type) {
+	case map[string][]any:
+		if len(keys) == 0 {
+			return nil
+		}
+		for _, y := range x[keys[0]] {
+			r = append(r, values(y, keys[1:])...)
+		}
+	case []map[string][]any:
+		for _, y := range x {
+			r = append(r, values(y, keys)...)
+		}
+	case []any:
+		for _, y := range x {
+			r = append(r, values(y, keys)...)
+		}
+	case []float64:
+		if len(keys) == 0 {
+			for _, y := range x {
+				r = append(r, y)
+			}
+		}
+	case []string:
+		if len(keys) == 0 {
+			for _, y := range x {
+				r = append(r, y)
+			}
+		}
+	case []bool:
+		if len(keys) == 0 {
+			for _, y := range x {
+				r = append(r, y)
+			}
+		}
+	default:
+		if len(keys) == 0 {
+			r = append(r, x)
+		}
+	}
+	return r
+}
+
+func intParam(s string, fallback int) (int, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	x, err := strconv.Atoi(s)
+	if err != nil || x < 0 {
+		return 0, fmt.Errorf("invalid number: %s", s)
+	}
+	return x, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("WARNING: Failed to write response: %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
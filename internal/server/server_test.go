@@ -0,0 +1,126 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/server"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+)
+
+func TestServer(t *testing.T) {
+	s := server.New(testutil.Load(t, "meta"), testutil.LoadGamestate(t))
+	get := func(t *testing.T, url string, v any) int {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v))
+		return rec.Code
+	}
+	type entity struct {
+		ID    string         `json:"id"`
+		Value map[string]any `json:"value"`
+	}
+	t.Run("should return index", func(t *testing.T) {
+		var got map[string][]string
+		status := get(t, "/", &got)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, got["endpoints"], "/countries")
+	})
+	t.Run("should return meta", func(t *testing.T) {
+		var got map[string]any
+		status := get(t, "/meta", &got)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []any{"Andromeda v3.12.5"}, got["version"])
+	})
+	t.Run("should return collection without deleted entities", func(t *testing.T) {
+		var got []entity
+		status := get(t, "/countries", &got)
+		assert.Equal(t, http.StatusOK, status)
+		require.Len(t, got, 3)
+		assert.Equal(t, "0", got[0].ID)
+		assert.Equal(t, []any{12500.5}, got[0].Value["military_power"])
+	})
+	t.Run("should filter collection", func(t *testing.T) {
+		var got []entity
+		status := get(t, "/planets?owner=0", &got)
+		assert.Equal(t, http.StatusOK, status)
+		var ids []string
+		for _, e := range got {
+			ids = append(ids, e.ID)
+		}
+		assert.Equal(t, []string{"1", "4"}, ids)
+	})
+	t.Run("should filter collection by all values of arrays and repeated keys", func(t *testing.T) {
+		for _, url := range []string{"/countries?owned_planets=4", "/countries?tech_status.technology=tech_lasers_2"} {
+			var got []entity
+			status := get(t, url, &got)
+			assert.Equal(t, http.StatusOK, status)
+			if assert.Len(t, got, 1, url) {
+				assert.Equal(t, "0", got[0].ID, url)
+			}
+		}
+	})
+	t.Run("should page through collection", func(t *testing.T) {
+		var got []entity
+		status := get(t, "/planets?offset=1&limit=2", &got)
+		assert.Equal(t, http.StatusOK, status)
+		var ids []string
+		for _, e := range got {
+			ids = append(ids, e.ID)
+		}
+		assert.Equal(t, []string{"2", "3"}, ids)
+	})
+	t.Run("should return entity", func(t *testing.T) {
+		var got map[string]any
+		status := get(t, "/planets/1", &got)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []any{"pc_gaia"}, got["planet_class"])
+	})
+	t.Run("should return value at path", func(t *testing.T) {
+		var got any
+		status := get(t, "/query?path=country.2.military_power", &got)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 15000.0, got)
+	})
+	t.Run("should return errors", func(t *testing.T) {
+		cases := []struct {
+			url    string
+			status int
+		}{
+			{"/unknown", http.StatusNotFound},
+			{"/countries/3", http.StatusNotFound},
+			{"/countries/99", http.StatusNotFound},
+			{"/countries/0.name", http.StatusNotFound},
+			{"/countries/abc", http.StatusNotFound},
+			{"/countries?limit=x", http.StatusBadRequest},
+			{"/query", http.StatusBadRequest},
+			{"/query?path=unknown", http.StatusNotFound},
+		}
+		for _, tc := range cases {
+			var got map[string]string
+			status := get(t, tc.url, &got)
+			assert.Equal(t, tc.status, status, tc.url)
+			assert.NotEmpty(t, got["error"], tc.url)
+		}
+	})
+	t.Run("should not set CORS header by default", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/meta", nil))
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+	t.Run("can set CORS header", func(t *testing.T) {
+		s := server.New(testutil.Load(t, "meta"), testutil.LoadGamestate(t))
+		s.AllowOrigin = "*"
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/countries/0", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
@@ -0,0 +1,296 @@
+// Package sqlite exports the gamestate of save files into SQLite databases.
+//
+// The major collections of the gamestate, e.g. countries and planets,
+// are normalized into relational tables, which reference each other by the IDs from the save.
+// The columns of these tables are the known fields from [stellaris.Collections]
+// and a data column with the complete entity as JSON.
+// All other sections of the gamestate are stored in a generic key/value table.
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// sqlTypes are the SQL types of the columns by field type.
+var sqlTypes = map[stellaris.FieldType]string{
+	stellaris.TextField:  "TEXT",
+	stellaris.IntField:   "INTEGER",
+	stellaris.FloatField: "REAL",
+	stellaris.BoolField:  "INTEGER",
+}
+
+// Tables which link entities with each other.
+const linkTablesSQL = `
+CREATE TABLE war_participants (
+	war_id INTEGER NOT NULL REFERENCES wars(id),
+	country_id INTEGER NOT NULL REFERENCES countries(id),
+	side TEXT NOT NULL,
+	call_type TEXT
+);
+CREATE INDEX war_participants_country_id ON war_participants(country_id);
+CREATE TABLE federation_members (
+	federation_id INTEGER NOT NULL REFERENCES federations(id),
+	country_id INTEGER NOT NULL REFERENCES countries(id)
+);
+CREATE INDEX federation_members_country_id ON federation_members(country_id);
+CREATE TABLE sections (
+	section TEXT NOT NULL,
+	id INTEGER,
+	value TEXT
+);
+CREATE INDEX sections_section_id ON sections(section, id);
+`
+
+// Export writes the gamestate into a new SQLite database at path.
+// An existing file at path will be replaced.
+// The database is written to a temporary file first,
+// so an existing file is kept when the export fails.
+func Export(path string, gamestate map[string][]any) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := export(tmp, gamestate); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func export(path string, gamestate map[string][]any) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := createTables(tx); err != nil {
+		return err
+	}
+	sections := make(map[string]bool)
+	for _, c := range stellaris.Collections {
+		sections[c.Section] = true
+		if err := insertEntities(tx, c, gamestate); err != nil {
+			return fmt.Errorf("table %s: %w", c.Name, err)
+		}
+	}
+	if err := insertWarParticipants(tx, gamestate); err != nil {
+		return err
+	}
+	if err := insertFederationMembers(tx, gamestate); err != nil {
+		return err
+	}
+	if err := insertSections(tx, gamestate, sections); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+func createTables(tx *sql.Tx) error {
+	for _, c := range stellaris.Collections {
+		defs := []string{"id INTEGER PRIMARY KEY"}
+		var indexes []string
+		for _, f := range c.Fields {
+			d := f.Name + " " + sqlTypes[f.Type]
+			if f.Ref != "" {
+				d += fmt.Sprintf(" REFERENCES %s(id)", f.Ref)
+				indexes = append(indexes, fmt.Sprintf("CREATE INDEX %s_%s ON %s(%s);", c.Name, f.Name, c.Name, f.Name))
+			}
+			defs = append(defs, d)
+		}
+		defs = append(defs, "data TEXT")
+		q := fmt.Sprintf("CREATE TABLE %s (\n\t%s\n);", c.Name, strings.Join(defs, ",\n\t"))
+		if _, err := tx.Exec(q + strings.Join(indexes, "\n")); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(linkTablesSQL)
+	return err
+}
+
+// insertEntities inserts all entities of collection c into it's table.
+// An ID which is repeated in the save is inserted once with it's first entity,
+// the same as in the model.
+func insertEntities(tx *sql.Tx, c stellaris.Collection, gamestate map[string][]any) error {
+	m, ok := tree.Object(gamestate, c.Section)
+	if !ok {
+		return nil
+	}
+	names := []string{"id"}
+	for _, f := range c.Fields {
+		names = append(names, f.Name)
+	}
+	names = append(names, "data")
+	q := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (?%s)",
+		c.Name,
+		strings.Join(names, ", "),
+		strings.Repeat(", ?", len(names)-1),
+	)
+	stmt, err := tx.Prepare(q)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, id := range tree.IDs(m) {
+		entity, ok := tree.Object(m, id)
+		if !ok {
+			continue // entity was deleted
+		}
+		args := []any{id}
+		for _, f := range c.Fields {
+			args = append(args, columnValue(f, entity))
+		}
+		data, err := json.Marshal(entity)
+		if err != nil {
+			return err
+		}
+		args = append(args, string(data))
+		if _, err := stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnValue returns the value for field f from an entity.
+// References which are not set are returned as null.
+func columnValue(f stellaris.Field, entity map[string][]any) any {
+	for _, p := range f.Paths() {
+		v, ok := tree.Get(entity, p)
+		if !ok {
+			continue
+		}
+		if f.Ref != "" {
+			id, ok := stellaris.ParseID(v)
+			if !ok {
+				continue
+			}
+			return id
+		}
+		switch x := v.(type) {
+		case float64:
+			return x
+		case bool:
+			if x {
+				return 1
+			}
+			return 0
+		case string:
+			return x
+		case map[string][]any:
+			return tree.Name(x)
+		}
+	}
+	return nil
+}
+
+func insertWarParticipants(tx *sql.Tx, gamestate map[string][]any) error {
+	wars, ok := tree.Object(gamestate, "war")
+	if !ok {
+		return nil
+	}
+	stmt, err := tx.Prepare("INSERT INTO war_participants (war_id, country_id, side, call_type) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, id := range tree.IDs(wars) {
+		war, ok := tree.Object(wars, id)
+		if !ok {
+			continue
+		}
+		for _, side := range []string{"attackers", "defenders"} {
+			for _, p := range tree.Objects(war[side]) {
+				country, ok := tree.Get(p, "country")
+				if !ok {
+					continue
+				}
+				callType, _ := tree.Get(p, "call_type")
+				if _, err := stmt.Exec(id, country, strings.TrimSuffix(side, "s"), callType); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func insertFederationMembers(tx *sql.Tx, gamestate map[string][]any) error {
+	federations, ok := tree.Object(gamestate, "federation")
+	if !ok {
+		return nil
+	}
+	stmt, err := tx.Prepare("INSERT INTO federation_members (federation_id, country_id) VALUES (?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, id := range tree.IDs(federations) {
+		federation, ok := tree.Object(federations, id)
+		if !ok {
+			continue
+		}
+		members, _ := tree.Get(federation, "members")
+		ids, _ := members.([]float64)
+		for _, country := range ids {
+			if _, err := stmt.Exec(id, country); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// insertSections inserts all sections of the gamestate which have no table into the sections table.
+func insertSections(tx *sql.Tx, gamestate map[string][]any, skip map[string]bool) error {
+	stmt, err := tx.Prepare("INSERT INTO sections (section, id, value) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	return tree.WalkSections(gamestate, func(section, id string, value any) error {
+		if skip[section] {
+			return nil
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		var x any
+		if id != "" {
+			x, err = strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = stmt.Exec(section, x, string(data))
+		return err
+	})
+}
@@ -0,0 +1,154 @@
+package sqlite_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/sqlite"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+)
+
+func TestExport(t *testing.T) {
+	gamestate := testutil.LoadGamestate(t)
+	p := filepath.Join(t.TempDir(), "out.db")
+	require.NoError(t, os.WriteFile(p, []byte("old"), 0644))
+	err := sqlite.Export(p, gamestate)
+	require.NoError(t, err)
+	db, err := sql.Open("sqlite", p)
+	require.NoError(t, err)
+	defer db.Close()
+	t.Run("should create tables for collections", func(t *testing.T) {
+		cases := []struct {
+			table string
+			count int
+		}{
+			{"countries", 3},
+			{"species", 3},
+			{"planets", 5},
+			{"pops", 4},
+			{"leaders", 2},
+			{"fleets", 5},
+			{"ships", 6},
+			{"wars", 1},
+			{"federations", 1},
+			{"war_participants", 3},
+			{"federation_members", 2},
+		}
+		for _, tc := range cases {
+			var got int
+			err := db.QueryRow("SELECT COUNT(*) FROM " + tc.table).Scan(&got)
+			if assert.NoError(t, err, tc.table) {
+				assert.Equal(t, tc.count, got, tc.table)
+			}
+		}
+	})
+	t.Run("can join tables by IDs", func(t *testing.T) {
+		rows, err := db.Query(`
+			SELECT p.name, c.name, s.name
+			FROM pops
+			JOIN planets p ON p.id = pops.planet_id
+			JOIN countries c ON c.id = p.owner_id
+			JOIN species s ON s.id = pops.species_id
+			WHERE pops.id = 2`)
+		require.NoError(t, err)
+		defer rows.Close()
+		require.True(t, rows.Next())
+		var planet, country, species string
+		require.NoError(t, rows.Scan(&planet, &country, &species))
+		assert.Equal(t, "NAME_Earth", planet)
+		assert.Equal(t, "SPEC_Human_adj Commonwealth", country)
+		assert.Equal(t, "SPEC_Human", species)
+	})
+	t.Run("should store values of columns", func(t *testing.T) {
+		var militaryPower float64
+		var federationID sql.NullInt64
+		err := db.QueryRow("SELECT military_power, federation_id FROM countries WHERE id = 2").Scan(&militaryPower, &federationID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 15000.0, militaryPower)
+			assert.False(t, federationID.Valid)
+		}
+		var isStation int
+		var systemID int
+		err = db.QueryRow("SELECT is_station, system_id FROM fleets WHERE id = 3").Scan(&isStation, &systemID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, isStation)
+			assert.Equal(t, 2, systemID)
+		}
+	})
+	t.Run("should store complete entities as JSON", func(t *testing.T) {
+		var got string
+		err := db.QueryRow("SELECT json_extract(data, '$.planet_class[0]') FROM planets WHERE id = 1").Scan(&got)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "pc_gaia", got)
+		}
+	})
+	t.Run("should store war participants", func(t *testing.T) {
+		var got int
+		err := db.QueryRow("SELECT country_id FROM war_participants WHERE side = 'defender'").Scan(&got)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 2, got)
+		}
+	})
+	t.Run("should store other sections as key/values", func(t *testing.T) {
+		var got int
+		err := db.QueryRow("SELECT COUNT(*) FROM sections WHERE section = 'galaxy'").Scan(&got)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, got)
+		}
+		var version string
+		err = db.QueryRow("SELECT value FROM sections WHERE section = 'version'").Scan(&version)
+		if assert.NoError(t, err) {
+			assert.Equal(t, `"Andromeda v3.12.5"`, version)
+		}
+		err = db.QueryRow("SELECT COUNT(*) FROM sections WHERE section IN ('country', 'planets.planet')").Scan(&got)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, got)
+		}
+	})
+}
+
+func TestExportFailure(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "out.db")
+	require.NoError(t, os.WriteFile(p, []byte("old"), 0644))
+	gamestate := map[string][]any{"country": {map[string][]any{"0": {map[string][]any{"name": {make(chan int)}}}}}}
+	err := sqlite.Export(p, gamestate)
+	require.Error(t, err)
+	t.Run("should keep existing file", func(t *testing.T) {
+		got, err := os.ReadFile(p)
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(got))
+	})
+	t.Run("should remove temporary file", func(t *testing.T) {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+}
+
+func TestExportRepeatedIDs(t *testing.T) {
+	gamestate := map[string][]any{"country": {map[string][]any{"0": {
+		map[string][]any{"name": {"first"}, "capital": {4294967295.0}},
+		map[string][]any{"name": {"second"}},
+	}}}}
+	p := filepath.Join(t.TempDir(), "out.db")
+	require.NoError(t, sqlite.Export(p, gamestate))
+	db, err := sql.Open("sqlite", p)
+	require.NoError(t, err)
+	defer db.Close()
+	rows, err := db.Query("SELECT name, capital_id FROM countries")
+	require.NoError(t, err)
+	defer rows.Close()
+	require.True(t, rows.Next())
+	var name string
+	var capital sql.NullInt64
+	require.NoError(t, rows.Scan(&name, &capital))
+	assert.Equal(t, "first", name)
+	assert.False(t, capital.Valid)
+	assert.False(t, rows.Next())
+}
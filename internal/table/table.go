@@ -0,0 +1,207 @@
+// Package table flattens collections of entities from the gamestate into tables,
+// e.g. for exporting them as CSV.
+//
+// Each entity of a collection becomes a row and each scalar value of an entity a column.
+// Nested values get dotted column names, e.g. "budget.current_month.income.jobs".
+// When a path has more than one value, e.g. for arrays or repeated keys,
+// all values are joined into one string.
+package table
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+)
+
+// DefaultSeparator is the default separator for joining multiple values.
+const DefaultSeparator = ";"
+
+// Options represents the options for creating a table.
+type Options struct {
+	// Columns are the dotted names of the columns in order.
+	// A name can also be a pattern as defined by [path.Match], e.g. "budget.*.income.*",
+	// which selects all matching columns in alphabetical order.
+	// All columns are selected when empty.
+	Columns []string
+	// Separator is used for joining multiple values. Defaults to [DefaultSeparator].
+	Separator string
+}
+
+// Table represents a flattened collection of entities.
+type Table struct {
+	// Columns are the names of the columns. The first column is always the ID.
+	Columns []string
+	// Rows contains the values for each entity in the order of the columns.
+	// Values are either float64, string, bool or nil for missing values.
+	Rows [][]any
+}
+
+// New returns a table for the collection at the dotted path in gamestate, e.g. "planets.planet".
+// Deleted entities are skipped.
+func New(gamestate map[string][]any, collection string, opt Options) (*Table, error) {
+	m, ok := tree.Object(gamestate, collection)
+	if !ok || !tree.IsIDMap(m) {
+		return nil, fmt.Errorf("no collection found at %s", collection)
+	}
+	sep := opt.Separator
+	if sep == "" {
+		sep = DefaultSeparator
+	}
+	var ids []string
+	var entities []map[string][]any
+	available := make(map[string]bool)
+	for _, id := range tree.IDs(m) {
+		for _, v := range m[id] {
+			o, ok := v.(map[string][]any)
+			if !ok {
+				continue // entity was deleted
+			}
+			e := make(map[string][]any)
+			flatten(e, "", o)
+			for k := range e {
+				available[k] = true
+			}
+			ids = append(ids, id)
+			entities = append(entities, e)
+		}
+	}
+	columns, err := selectColumns(available, opt.Columns)
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{Columns: append([]string{"id"}, columns...)}
+	for i, e := range entities {
+		id, _ := strconv.ParseFloat(ids[i], 64)
+		row := []any{id}
+		for _, c := range columns {
+			row = append(row, join(e[c], sep))
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, nil
+}
+
+// WriteCSV writes the table as CSV with a header row to w.
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(t.Columns))
+	for _, row := range t.Rows {
+		for i, v := range row {
+			record[i] = Format(v)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// flatten adds all scalar values of object o to e with their dotted path.
+func flatten(e map[string][]any, prefix string, o map[string][]any) {
+	for k, vv := range o {
+		p := k
+		if prefix != "" {
+			p = prefix + "." + k
+		}
+		for _, v := range vv {
+			flattenValue(e, p, v)
+		}
+	}
+}
+
+func flattenValue(e map[string][]any, p string, v any) {
+	switch x := v.(type) {
+	case map[string][]any:
+		flatten(e, p, x)
+	case []map[string][]any:
+		for _, o := range x {
+			flatten(e, p, o)
+		}
+	case []float64:
+		for _, f := range x {
+			e[p] = append(e[p], f)
+		}
+	case []string:
+		for _, s := range x {
+			e[p] = append(e[p], s)
+		}
+	case []bool:
+		for _, b := range x {
+			e[p] = append(e[p], b)
+		}
+	case []any:
+		for _, y := range x {
+			flattenValue(e, p, y)
+		}
+	default:
+		e[p] = append(e[p], x)
+	}
+}
+
+// selectColumns returns the selected columns from the available columns.
+func selectColumns(available map[string]bool, selected []string) ([]string, error) {
+	all := make([]string, 0, len(available))
+	for k := range available {
+		all = append(all, k)
+	}
+	slices.Sort(all)
+	if len(selected) == 0 {
+		return all, nil
+	}
+	var columns []string
+	for _, s := range selected {
+		if !strings.ContainsAny(s, "*?[") {
+			columns = append(columns, s)
+			continue
+		}
+		if _, err := path.Match(s, ""); err != nil {
+			return nil, fmt.Errorf("invalid column pattern %q: %w", s, err)
+		}
+		for _, c := range all {
+			if ok, _ := path.Match(s, c); ok {
+				columns = append(columns, c)
+			}
+		}
+	}
+	return columns, nil
+}
+
+// join returns the only value of vv or all values joined with sep as string.
+func join(vv []any, sep string) any {
+	switch len(vv) {
+	case 0:
+		return nil
+	case 1:
+		return vv[0]
+	}
+	s := make([]string, len(vv))
+	for i, v := range vv {
+		s[i] = Format(v)
+	}
+	return strings.Join(s, sep)
+}
+
+// Format returns a value of a table as string.
+func Format(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case string:
+		return x
+	}
+	return fmt.Sprint(v)
+}
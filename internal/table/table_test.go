@@ -0,0 +1,83 @@
+package table_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/table"
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+)
+
+func TestNew(t *testing.T) {
+	gamestate := testutil.LoadGamestate(t)
+	t.Run("should create row for each entity", func(t *testing.T) {
+		x, err := table.New(gamestate, "planets.planet", table.Options{})
+		require.NoError(t, err)
+		assert.Len(t, x.Rows, 5)
+		assert.Equal(t, "id", x.Columns[0])
+		assert.Equal(t, 1.0, x.Rows[0][0])
+		assert.Contains(t, x.Columns, "coordinate.origin")
+	})
+	t.Run("should skip deleted entities", func(t *testing.T) {
+		x, err := table.New(gamestate, "country", table.Options{})
+		require.NoError(t, err)
+		assert.Len(t, x.Rows, 3)
+	})
+	t.Run("should select columns in order", func(t *testing.T) {
+		x, err := table.New(gamestate, "country", table.Options{
+			Columns: []string{"military_power", "name.key", "unknown"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"id", "military_power", "name.key", "unknown"}, x.Columns)
+		assert.Equal(t, []any{0.0, 12500.5, "Blooms of Gaea", nil}, x.Rows[0])
+	})
+	t.Run("should select columns by pattern", func(t *testing.T) {
+		x, err := table.New(gamestate, "country", table.Options{
+			Columns: []string{"budget.current_month.income.country_base.*"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"id",
+			"budget.current_month.income.country_base.energy",
+			"budget.current_month.income.country_base.minerals",
+		}, x.Columns)
+	})
+	t.Run("should join multiple values", func(t *testing.T) {
+		x, err := table.New(gamestate, "country", table.Options{
+			Columns:   []string{"owned_planets", "tech_status.level"},
+			Separator: "|",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []any{0.0, "1|4", "1|1|1|3"}, x.Rows[0])
+	})
+	t.Run("should return error for invalid pattern", func(t *testing.T) {
+		_, err := table.New(gamestate, "country", table.Options{Columns: []string{"name.["}})
+		assert.Error(t, err)
+	})
+	t.Run("should return error when collection not found", func(t *testing.T) {
+		_, err := table.New(gamestate, "unknown", table.Options{})
+		assert.Error(t, err)
+		_, err = table.New(gamestate, "version", table.Options{})
+		assert.Error(t, err)
+	})
+}
+
+func TestWriteCSV(t *testing.T) {
+	x := &table.Table{
+		Columns: []string{"id", "name", "power", "is_station"},
+		Rows: [][]any{
+			{0.0, "Alpha, Beta", 4294967295.0, true},
+			{1.0, nil, 0.5, false},
+		},
+	}
+	var buf bytes.Buffer
+	err := x.WriteCSV(&buf)
+	require.NoError(t, err)
+	want := "id,name,power,is_station\n" +
+		"0,\"Alpha, Beta\",4294967295,true\n" +
+		"1,,0.5,false\n"
+	assert.Equal(t, want, buf.String())
+}
@@ -0,0 +1,37 @@
+// Package testutil provides helpers for tests, e.g. for loading the save game fixtures.
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/parser"
+)
+
+// Path returns the path of a file in the testdata directory of the repository, e.g. "meta".
+func Path(name string) string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "testdata", name)
+}
+
+// Load returns the parsed contents of a file in the testdata directory, e.g. "meta".
+// It fails the test when the file can not be loaded.
+func Load(t testing.TB, name string) map[string][]any {
+	t.Helper()
+	f, err := os.Open(Path(name))
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := parser.NewParser(f).Parse()
+	require.NoError(t, err)
+	return data
+}
+
+// LoadGamestate returns the parsed gamestate from the testdata directory.
+func LoadGamestate(t testing.TB) map[string][]any {
+	t.Helper()
+	return Load(t, "gamestate")
+}
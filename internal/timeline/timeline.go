@@ -0,0 +1,219 @@
+// Package timeline extracts the values of metrics from a series of save games,
+// e.g. the military power of all empires from the autosaves of a campaign.
+//
+// Metrics are dotted paths in the gamestate, e.g. "country.0.military_power".
+// Each part of a path can also be a pattern as defined by [path.Match],
+// e.g. "country.*.military_power", which results in one series for each matching path.
+package timeline
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/table"
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+// Sample represents the values of the metrics in one save game.
+type Sample struct {
+	Save string `json:"save"` // path of the save game
+	Name string `json:"name"` // name of the player's empire
+	Date string `json:"date"` // in-game date
+	// Values are the values of all series by their path.
+	// Values are either float64, string or bool.
+	Values map[string]any `json:"values"`
+}
+
+// Timeline represents the values of metrics over a series of save games.
+type Timeline struct {
+	metrics []string
+	samples []Sample
+}
+
+// New returns a new timeline for the given metrics.
+func New(metrics []string) (*Timeline, error) {
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("no metrics")
+	}
+	for _, m := range metrics {
+		for _, p := range strings.Split(m, ".") {
+			if _, err := path.Match(p, ""); err != nil || p == "" {
+				return nil, fmt.Errorf("invalid metric: %s", m)
+			}
+		}
+	}
+	t := &Timeline{metrics: slices.Clone(metrics)}
+	return t, nil
+}
+
+// Add adds the values of the metrics from a parsed save game.
+// Samples are ordered by the in-game date from meta.
+func (t *Timeline) Add(save string, meta, gamestate map[string][]any) {
+	si := stellaris.NewSaveInfo(meta)
+	s := Sample{Save: save, Name: si.Name, Date: si.Date, Values: make(map[string]any)}
+	for _, m := range t.metrics {
+		expand(s.Values, "", gamestate, strings.Split(m, "."))
+	}
+	i, _ := slices.BinarySearchFunc(t.samples, s, func(a, b Sample) int {
+		if c := stellaris.CompareDates(a.Date, b.Date); c != 0 {
+			return c
+		}
+		return -1 // keep the order of samples with the same date
+	})
+	t.samples = slices.Insert(t.samples, i, s)
+}
+
+// Samples returns the samples of the timeline ordered by their date.
+func (t *Timeline) Samples() []Sample {
+	return t.samples
+}
+
+// Series returns the paths of all series in the timeline.
+// They are ordered by metric and then by path with IDs in numerical order.
+func (t *Timeline) Series() []string {
+	seen := make(map[string]bool)
+	var series []string
+	for _, s := range t.samples {
+		for p := range s.Values {
+			if !seen[p] {
+				seen[p] = true
+				series = append(series, p)
+			}
+		}
+	}
+	slices.SortFunc(series, func(a, b string) int {
+		if c := t.metricIndex(a) - t.metricIndex(b); c != 0 {
+			return c
+		}
+		return comparePaths(a, b)
+	})
+	return series
+}
+
+// metricIndex returns the index of the first metric matching the path of a series.
+func (t *Timeline) metricIndex(p string) int {
+	parts := strings.Split(p, ".")
+	for i, m := range t.metrics {
+		patterns := strings.Split(m, ".")
+		if len(patterns) != len(parts) {
+			continue
+		}
+		matched := true
+		for j := range patterns {
+			if ok, _ := path.Match(patterns[j], parts[j]); !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return len(t.metrics)
+}
+
+// WriteCSV writes the timeline as CSV with one row per save game and one column per series.
+// Missing values are empty.
+func (t *Timeline) WriteCSV(w io.Writer) error {
+	series := t.Series()
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"date", "name", "save"}, series...)); err != nil {
+		return err
+	}
+	for _, s := range t.samples {
+		record := []string{s.Date, s.Name, s.Save}
+		for _, p := range series {
+			record = append(record, table.Format(s.Values[p]))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// point represents the value of a series in one save game for JSON.
+type point struct {
+	Date  string `json:"date"`
+	Save  string `json:"save"`
+	Value any    `json:"value"`
+}
+
+// series represents a series with it's values for JSON.
+type series struct {
+	Path   string  `json:"path"`
+	Points []point `json:"points"`
+}
+
+// WriteJSON writes the timeline as JSON with a list of points for each series.
+// Save games without a value for a series have no point in that series.
+func (t *Timeline) WriteJSON(w io.Writer) error {
+	r := make([]series, 0)
+	for _, p := range t.Series() {
+		x := series{Path: p, Points: make([]point, 0)}
+		for _, s := range t.samples {
+			if v, ok := s.Values[p]; ok {
+				x.Points = append(x.Points, point{Date: s.Date, Save: s.Save, Value: v})
+			}
+		}
+		r = append(r, x)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(map[string]any{"series": r})
+}
+
+// expand adds the scalar values at all paths in o matching patterns to values.
+func expand(values map[string]any, prefix string, o map[string][]any, patterns []string) {
+	for _, k := range tree.Keys(o) {
+		if ok, _ := path.Match(patterns[0], k); !ok {
+			continue
+		}
+		vv := o[k]
+		if len(vv) == 0 {
+			continue
+		}
+		p := k
+		if prefix != "" {
+			p = prefix + "." + k
+		}
+		switch x := vv[0].(type) {
+		case map[string][]any:
+			if len(patterns) > 1 {
+				expand(values, p, x, patterns[1:])
+			}
+		case float64, string, bool:
+			if len(patterns) == 1 {
+				values[p] = x
+			}
+		}
+	}
+}
+
+// comparePaths compares two dotted paths part by part and IDs by their numerical value.
+func comparePaths(a, b string) int {
+	x, y := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] == y[i] {
+			continue
+		}
+		if tree.IsID(x[i]) && tree.IsID(y[i]) {
+			n, _ := strconv.ParseUint(x[i], 10, 64)
+			m, _ := strconv.ParseUint(y[i], 10, 64)
+			if n < m {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(x[i], y[i])
+	}
+	return len(x) - len(y)
+}
@@ -0,0 +1,78 @@
+package timeline_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+	"github.com/ErikKalkoken/stellaris-tool/internal/timeline"
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+)
+
+func TestTimeline(t *testing.T) {
+	newTimeline := func(t *testing.T, metrics ...string) *timeline.Timeline {
+		tl, err := timeline.New(metrics)
+		require.NoError(t, err)
+		later, later2 := testutil.Load(t, "meta"), testutil.Load(t, "gamestate")
+		earlier, earlier2 := testutil.Load(t, "meta"), testutil.Load(t, "gamestate")
+		earlier["date"] = []any{"2400.01.01"}
+		c, ok := tree.Object(earlier2, "country.0")
+		require.True(t, ok)
+		c["military_power"] = []any{800.0}
+		earlier2["country"][0].(map[string][]any)["2"] = []any{nil} // country was not founded yet
+		tl.Add("later.sav", later, later2)
+		tl.Add("earlier.sav", earlier, earlier2)
+		return tl
+	}
+	t.Run("should order samples by date", func(t *testing.T) {
+		tl := newTimeline(t, "country.0.military_power")
+		var dates []string
+		for _, s := range tl.Samples() {
+			dates = append(dates, s.Date)
+		}
+		assert.Equal(t, []string{"2400.01.01", "2415.06.06"}, dates)
+		assert.Equal(t, "earlier.sav", tl.Samples()[0].Save)
+		assert.Equal(t, "Blooms of Gaea 2", tl.Samples()[0].Name)
+		assert.Equal(t, map[string]any{"country.0.military_power": 800.0}, tl.Samples()[0].Values)
+	})
+	t.Run("should expand patterns into series", func(t *testing.T) {
+		tl := newTimeline(t, "country.*.military_power", "galaxy.shape")
+		assert.Equal(t, []string{
+			"country.0.military_power",
+			"country.1.military_power",
+			"country.2.military_power",
+			"galaxy.shape",
+		}, tl.Series())
+	})
+	t.Run("should write CSV", func(t *testing.T) {
+		tl := newTimeline(t, "country.*.military_power")
+		var buf bytes.Buffer
+		require.NoError(t, tl.WriteCSV(&buf))
+		assert.Equal(t,
+			"date,name,save,country.0.military_power,country.1.military_power,country.2.military_power\n"+
+				"2400.01.01,Blooms of Gaea 2,earlier.sav,800,8000,\n"+
+				"2415.06.06,Blooms of Gaea 2,later.sav,12500.5,8000,15000\n",
+			buf.String())
+	})
+	t.Run("should write JSON", func(t *testing.T) {
+		tl := newTimeline(t, "country.2.military_power")
+		var buf bytes.Buffer
+		require.NoError(t, tl.WriteJSON(&buf))
+		assert.JSONEq(t, `{"series": [{
+			"path": "country.2.military_power",
+			"points": [{"date": "2415.06.06", "save": "later.sav", "value": 15000}]
+		}]}`, buf.String())
+		assert.Contains(t, buf.String(), "\n    \"series\"")
+	})
+	t.Run("should reject invalid metrics", func(t *testing.T) {
+		for _, m := range []string{"country.[.military_power", "country..x"} {
+			_, err := timeline.New([]string{m})
+			assert.Error(t, err, m)
+		}
+		_, err := timeline.New(nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,228 @@
+// Package tree provides helpers for working with the data of parsed save files.
+//
+// Parsed data is a nested map of keys to value slices as returned by the parser.
+// Many sections of a save file are collections of entities keyed by their numeric ID,
+// which are called ID maps here, e.g. country={ 0={...} 1={...} }.
+package tree
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// IsID reports whether key is a numeric ID.
+func IsID(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsIDMap reports whether m is a non-empty collection of entities keyed by their ID.
+func IsIDMap(m map[string][]any) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for k := range m {
+		if !IsID(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// Keys returns the keys of m in alphabetical order.
+func Keys(m map[string][]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// IDs returns the keys of the ID map m in ascending numerical order.
+func IDs(m map[string][]any) []string {
+	ids := Keys(m)
+	slices.SortFunc(ids, func(a, b string) int {
+		x, _ := strconv.ParseUint(a, 10, 64)
+		y, _ := strconv.ParseUint(b, 10, 64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	})
+	return ids
+}
+
+// Get returns the first value at the dotted path in m, e.g. "coordinate.origin",
+// and reports whether it was found.
+func Get(m map[string][]any, path string) (any, bool) {
+	var v any = m
+	for _, k := range strings.Split(path, ".") {
+		o, ok := v.(map[string][]any)
+		if !ok {
+			return nil, false
+		}
+		vv, ok := o[k]
+		if !ok || len(vv) == 0 {
+			return nil, false
+		}
+		v = vv[0]
+	}
+	return v, true
+}
+
+// Object returns the first value at the dotted path in m as object
+// and reports whether it was found.
+func Object(m map[string][]any, path string) (map[string][]any, bool) {
+	v, ok := Get(m, path)
+	if !ok {
+		return nil, false
+	}
+	o, ok := v.(map[string][]any)
+	return o, ok
+}
+
+// Objects returns all objects from a list of values,
+// which can contain single objects and arrays of objects.
+func Objects(vv []any) []map[string][]any {
+	var oo []map[string][]any
+	for _, v := range vv {
+		switch x := v.(type) {
+		case map[string][]any:
+			oo = append(oo, x)
+		case []map[string][]any:
+			oo = append(oo, x...)
+		}
+	}
+	return oo
+}
+
+// Name returns the name of an entity from it's name value.
+//
+// Names in save files are either plain strings or objects with a localization key,
+// which can have variables, e.g. name={ key="%ADJ%" variables={...} }.
+// Since localizations are not available, the key or the names of the variables are returned.
+// Returns an empty string when v has no name.
+func Name(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case map[string][]any:
+		if fn, ok := Get(x, "full_names"); ok {
+			return Name(fn)
+		}
+		if first, ok := Get(x, "first_name"); ok {
+			second, _ := Get(x, "second_name")
+			return strings.TrimSpace(Name(first) + " " + Name(second))
+		}
+		key, _ := Get(x, "key")
+		s, _ := key.(string)
+		if !strings.Contains(s, "%") {
+			return s
+		}
+		vars := variableNames(x)
+		if len(vars) == 0 {
+			return s
+		}
+		return strings.Join(vars, " ")
+	}
+	return ""
+}
+
+// variableNames returns the names of all variables of a localization.
+func variableNames(m map[string][]any) []string {
+	var names []string
+	for _, v := range m["variables"] {
+		var vars []map[string][]any
+		switch x := v.(type) {
+		case []map[string][]any:
+			vars = x
+		case map[string][]any:
+			vars = []map[string][]any{x}
+		}
+		for _, o := range vars {
+			if value, ok := Get(o, "value"); ok {
+				if s := Name(value); s != "" {
+					names = append(names, s)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// WalkSections calls fn for each top-level value of data in alphabetical order of the sections.
+//
+// Sections which are collections of entities are walked per entity with their ID.
+// These are sections keyed by ID, e.g. country={ 0={...} },
+// or which contain such collections, e.g. planets={ planet={ 0={...} } } with the section "planets.planet".
+// The ID is empty for all other sections.
+func WalkSections(data map[string][]any, fn func(section, id string, value any) error) error {
+	for _, k := range Keys(data) {
+		for _, v := range data[k] {
+			if err := walkSection(k, v, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func walkSection(section string, v any, fn func(section, id string, value any) error) error {
+	m, ok := v.(map[string][]any)
+	if !ok {
+		return fn(section, "", v)
+	}
+	if IsIDMap(m) {
+		return walkEntities(section, m, fn)
+	}
+	if !isCollectionOfIDMaps(m) {
+		return fn(section, "", v)
+	}
+	for _, k := range Keys(m) {
+		for _, x := range m[k] {
+			if err := walkEntities(section+"."+k, x.(map[string][]any), fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func walkEntities(section string, m map[string][]any, fn func(section, id string, value any) error) error {
+	for _, id := range IDs(m) {
+		for _, x := range m[id] {
+			if err := fn(section, id, x); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isCollectionOfIDMaps reports whether all values of m are ID maps.
+func isCollectionOfIDMaps(m map[string][]any) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for _, vv := range m {
+		for _, v := range vv {
+			x, ok := v.(map[string][]any)
+			if !ok || !IsIDMap(x) {
+				return false
+			}
+		}
+	}
+	return true
+}
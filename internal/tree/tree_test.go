@@ -0,0 +1,128 @@
+package tree_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+)
+
+func TestIsIDMap(t *testing.T) {
+	cases := []struct {
+		name string
+		in   map[string][]any
+		want bool
+	}{
+		{"ID map", map[string][]any{"0": {1.0}, "12": {nil}, "4294967295": {2.0}}, true},
+		{"regular object", map[string][]any{"0": {1.0}, "name": {"x"}}, false},
+		{"negative numbers", map[string][]any{"-1": {1.0}}, false},
+		{"empty", map[string][]any{}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tree.IsIDMap(tc.in))
+		})
+	}
+}
+
+func TestIDs(t *testing.T) {
+	m := map[string][]any{"10": {}, "9": {}, "100": {}, "0": {}}
+	assert.Equal(t, []string{"0", "9", "10", "100"}, tree.IDs(m))
+}
+
+func TestKeys(t *testing.T) {
+	m := map[string][]any{"bravo": {}, "alpha": {}, "1": {}}
+	assert.Equal(t, []string{"1", "alpha", "bravo"}, tree.Keys(m))
+}
+
+func TestGet(t *testing.T) {
+	m := map[string][]any{
+		"alpha": {map[string][]any{"bravo": {1.0, 2.0}}},
+		"empty": {},
+	}
+	t.Run("can get nested value", func(t *testing.T) {
+		got, ok := tree.Get(m, "alpha.bravo")
+		if assert.True(t, ok) {
+			assert.Equal(t, 1.0, got)
+		}
+	})
+	t.Run("can get object", func(t *testing.T) {
+		got, ok := tree.Object(m, "alpha")
+		if assert.True(t, ok) {
+			assert.Equal(t, map[string][]any{"bravo": {1.0, 2.0}}, got)
+		}
+	})
+	t.Run("should report missing values", func(t *testing.T) {
+		for _, p := range []string{"charlie", "alpha.charlie", "alpha.bravo.charlie", "empty"} {
+			_, ok := tree.Get(m, p)
+			assert.False(t, ok, p)
+		}
+	})
+}
+
+func TestObjects(t *testing.T) {
+	a := map[string][]any{"x": {1.0}}
+	b := map[string][]any{"x": {2.0}}
+	c := map[string][]any{"x": {3.0}}
+	got := tree.Objects([]any{a, []map[string][]any{b, c}, 5.0, nil})
+	assert.Equal(t, []map[string][]any{a, b, c}, got)
+}
+
+func TestName(t *testing.T) {
+	cases := []struct {
+		name string
+		in   any
+		want string
+	}{
+		{"plain string", "Earth", "Earth"},
+		{"key", map[string][]any{"key": {"NAME_Sol"}}, "NAME_Sol"},
+		{
+			"key with variables",
+			map[string][]any{
+				"key": {"%ADJECTIVE%"},
+				"variables": {[]map[string][]any{
+					{"key": {"adjective"}, "value": {map[string][]any{"key": {"Kel-Azaan"}}}},
+					{"key": {"1"}, "value": {map[string][]any{"key": {"Republic"}}}},
+				}},
+			},
+			"Kel-Azaan Republic",
+		},
+		{"full names", map[string][]any{"full_names": {map[string][]any{"key": {"Jorg Xu"}}}}, "Jorg Xu"},
+		{"first and second name", map[string][]any{"first_name": {"Jorg"}, "second_name": {"Xu"}}, "Jorg Xu"},
+		{"no name", 5.0, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tree.Name(tc.in))
+		})
+	}
+}
+
+func TestWalkSections(t *testing.T) {
+	data := map[string][]any{
+		"version": {"v3.12.5"},
+		"country": {map[string][]any{"10": {"b"}, "2": {"a"}}},
+		"planets": {map[string][]any{"planet": {map[string][]any{"5": {"c"}}}}},
+		"flags":   {map[string][]any{"alpha": {1.0}}},
+	}
+	type entry struct {
+		section, id string
+		value       any
+	}
+	var got []entry
+	err := tree.WalkSections(data, func(section, id string, value any) error {
+		got = append(got, entry{section, id, value})
+		return nil
+	})
+	if assert.NoError(t, err) {
+		want := []entry{
+			{"country", "2", "a"},
+			{"country", "10", "b"},
+			{"flags", "", map[string][]any{"alpha": {1.0}}},
+			{"planets.planet", "5", "c"},
+			{"version", "", "v3.12.5"},
+		}
+		assert.Equal(t, want, got)
+	}
+}
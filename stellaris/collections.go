@@ -0,0 +1,130 @@
+package stellaris
+
+import "strings"
+
+// Collection represents a major collection of entities in the gamestate.
+type Collection struct {
+	Name    string  // plural name, e.g. "countries"
+	Section string  // dotted path to the collection in the gamestate, e.g. "country"
+	Fields  []Field // known fields of the entities, which are used by exports
+}
+
+// FieldType represents the type of the values of a field.
+type FieldType uint
+
+const (
+	TextField FieldType = iota
+	IntField
+	FloatField
+	BoolField
+)
+
+// Field represents a known field of the entities of a collection.
+type Field struct {
+	Name string // name of the field in exports, e.g. "owner_id"
+	Path string // dotted path to the value within an entity. Alternatives can be separated by "|".
+	Type FieldType
+	Ref  string // name of the referenced collection for IDs, e.g. "countries"
+}
+
+// Paths returns the alternative paths to the value of a field.
+func (f Field) Paths() []string {
+	return strings.Split(f.Path, "|")
+}
+
+// Collections are the major collections of entities in the gamestate.
+var Collections = []Collection{
+	{"countries", "country", []Field{
+		{"name", "name", TextField, ""},
+		{"adjective", "adjective", TextField, ""},
+		{"type", "type", TextField, ""},
+		{"personality", "personality", TextField, ""},
+		{"capital_id", "capital", IntField, "planets"},
+		{"founder_species_id", "founder_species_ref", IntField, "species"},
+		{"ruler_id", "ruler", IntField, "leaders"},
+		{"federation_id", "federation", IntField, "federations"},
+		{"military_power", "military_power", FloatField, ""},
+		{"economy_power", "economy_power", FloatField, ""},
+		{"tech_power", "tech_power", FloatField, ""},
+		{"victory_rank", "victory_rank", IntField, ""},
+		{"victory_score", "victory_score", FloatField, ""},
+		{"fleet_size", "fleet_size", IntField, ""},
+		{"empire_size", "empire_size", IntField, ""},
+		{"num_sapient_pops", "num_sapient_pops", IntField, ""},
+	}},
+	{"species", "species_db", []Field{
+		{"name", "name", TextField, ""},
+		{"plural", "plural", TextField, ""},
+		{"adjective", "adjective", TextField, ""},
+		{"class", "class", TextField, ""},
+		{"portrait", "portrait", TextField, ""},
+		{"home_planet_id", "home_planet", IntField, "planets"},
+	}},
+	{"planets", "planets.planet", []Field{
+		{"name", "name", TextField, ""},
+		{"planet_class", "planet_class", TextField, ""},
+		{"planet_size", "planet_size", IntField, ""},
+		{"system_id", "coordinate.origin", IntField, ""},
+		{"x", "coordinate.x", FloatField, ""},
+		{"y", "coordinate.y", FloatField, ""},
+		{"owner_id", "owner", IntField, "countries"},
+		{"controller_id", "controller", IntField, "countries"},
+		{"original_owner_id", "original_owner", IntField, "countries"},
+		{"num_sapient_pops", "num_sapient_pops", IntField, ""},
+		{"stability", "stability", FloatField, ""},
+	}},
+	{"pops", "pop", []Field{
+		{"species_id", "species|species_index", IntField, "species"},
+		{"planet_id", "planet", IntField, "planets"},
+		{"job", "job", TextField, ""},
+		{"category", "category", TextField, ""},
+	}},
+	{"leaders", "leaders", []Field{
+		{"name", "name", TextField, ""},
+		{"class", "class", TextField, ""},
+		{"level", "level", IntField, ""},
+		{"age", "age", IntField, ""},
+		{"species_id", "species|species_index", IntField, "species"},
+		{"country_id", "country", IntField, "countries"},
+	}},
+	{"fleets", "fleet", []Field{
+		{"name", "name", TextField, ""},
+		{"owner_id", "owner", IntField, "countries"},
+		{"system_id", "combat.coordinate.origin|movement_manager.coordinate.origin", IntField, ""},
+		{"military_power", "military_power", FloatField, ""},
+		{"is_station", "station", BoolField, ""},
+		{"is_civilian", "civilian", BoolField, ""},
+	}},
+	{"ships", "ships", []Field{
+		{"name", "name", TextField, ""},
+		{"fleet_id", "fleet", IntField, "fleets"},
+		{"leader_id", "leader", IntField, "leaders"},
+		{"ship_design_id", "ship_design", IntField, ""},
+		{"hitpoints", "hitpoints", FloatField, ""},
+	}},
+	{"wars", "war", []Field{
+		{"name", "name", TextField, ""},
+		{"start_date", "start_date", TextField, ""},
+		{"attacker_war_goal", "attacker_war_goal.type", TextField, ""},
+		{"defender_war_goal", "defender_war_goal.type", TextField, ""},
+		{"attacker_war_exhaustion", "attacker_war_exhaustion", FloatField, ""},
+		{"defender_war_exhaustion", "defender_war_exhaustion", FloatField, ""},
+	}},
+	{"federations", "federation", []Field{
+		{"name", "name", TextField, ""},
+		{"federation_type", "federation_progression.federation_type", TextField, ""},
+		{"level", "federation_progression.level", IntField, ""},
+		{"leader_id", "leader", IntField, "countries"},
+		{"start_date", "start_date", TextField, ""},
+	}},
+}
+
+// FindCollection returns the collection with the given name and reports whether it was found.
+func FindCollection(name string) (Collection, bool) {
+	for _, c := range Collections {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Collection{}, false
+}
@@ -0,0 +1,581 @@
+package stellaris
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+)
+
+// NoID is the ID of references which are not set.
+const NoID = -1
+
+// unsetID is used in save files for references which are not set.
+const unsetID = 4294967295
+
+// Gamestate represents the major collections of the gamestate of a save game
+// as typed model. Entities are mapped by their ID and reference each other by ID.
+// Deleted entities are not included.
+type Gamestate struct {
+	Date        string // in-game date, e.g. "2415.06.06"
+	Countries   map[int]*Country
+	Species     map[int]*Species
+	Planets     map[int]*Planet
+	Pops        map[int]*Pop
+	Leaders     map[int]*Leader
+	Fleets      map[int]*Fleet
+	Ships       map[int]*Ship
+	Wars        map[int]*War
+	Federations map[int]*Federation
+	Systems     map[int]*System
+	Starbases   map[int]*Starbase
+	Bypasses    map[int]*Bypass
+}
+
+// Country represents an empire or another kind of country, e.g. a pirate faction.
+type Country struct {
+	ID               int
+	Name             string
+	Adjective        string
+	Type             string
+	Personality      string
+	CapitalID        int
+	FounderSpeciesID int
+	RulerID          int
+	FederationID     int
+	MilitaryPower    float64
+	EconomyPower     float64
+	TechPower        float64
+	VictoryRank      int
+	VictoryScore     float64
+	FleetSize        int
+	EmpireSize       int
+	OwnedPlanetIDs   []int
+	// Income is the monthly income by resource from all sources, e.g. "energy".
+	Income    map[string]float64
+	TechCount int // number of researched technologies
+	Relations []Relation
+	// FlagColors are the names of the colors of the flag, e.g. "dark_red".
+	// Colors which are not used are "null".
+	FlagColors []string
+}
+
+// Relation represents the relation of a country to another country.
+type Relation struct {
+	CountryID int
+	Opinion   float64
+	// Statuses are the active statuses of the relation in alphabetical order,
+	// e.g. "alliance", "communications" or "hostile".
+	Statuses []string
+}
+
+// HasStatus reports whether the relation has the status s.
+func (r Relation) HasStatus(s string) bool {
+	return slices.Contains(r.Statuses, s)
+}
+
+// Species represents a species.
+type Species struct {
+	ID           int
+	Name         string
+	Plural       string
+	Adjective    string
+	Class        string
+	Portrait     string
+	Traits       []string
+	HomePlanetID int
+}
+
+// Planet represents a planet.
+type Planet struct {
+	ID              int
+	Name            string
+	Class           string
+	Size            int
+	SystemID        int
+	OwnerID         int
+	ControllerID    int
+	OriginalOwnerID int
+	Stability       float64
+	PopIDs          []int
+}
+
+// Pop represents a pop living on a planet.
+type Pop struct {
+	ID        int
+	SpeciesID int
+	PlanetID  int
+	Job       string
+	Category  string
+}
+
+// Leader represents a leader.
+type Leader struct {
+	ID        int
+	Name      string
+	Class     string
+	Level     int
+	Age       int
+	SpeciesID int
+	CountryID int
+}
+
+// Fleet represents a fleet or a station.
+type Fleet struct {
+	ID            int
+	Name          string
+	OwnerID       int
+	SystemID      int
+	MilitaryPower float64
+	IsStation     bool
+	IsCivilian    bool
+	ShipIDs       []int
+}
+
+// Ship represents a ship.
+type Ship struct {
+	ID        int
+	Name      string
+	FleetID   int
+	LeaderID  int
+	DesignID  int
+	Hitpoints float64
+}
+
+// War represents a war.
+type War struct {
+	ID                    int
+	Name                  string
+	StartDate             string
+	Attackers             []WarParticipant
+	Defenders             []WarParticipant
+	AttackerWarGoal       string
+	DefenderWarGoal       string
+	AttackerWarExhaustion float64
+	DefenderWarExhaustion float64
+	Battles               []Battle
+}
+
+// Battle represents a battle of a war.
+type Battle struct {
+	Date                  string
+	Type                  string // e.g. "ships" or "armies"
+	SystemID              int
+	PlanetID              int
+	AttackerIDs           []int // IDs of the attacking countries
+	DefenderIDs           []int // IDs of the defending countries
+	AttackerVictory       bool
+	AttackerWarExhaustion float64
+	DefenderWarExhaustion float64
+	AttackerLosses        float64
+	DefenderLosses        float64
+}
+
+// WarParticipant represents a country participating in a war.
+type WarParticipant struct {
+	CountryID int
+	CallType  string
+}
+
+// Federation represents a federation.
+type Federation struct {
+	ID        int
+	Name      string
+	Type      string
+	Level     int
+	LeaderID  int
+	MemberIDs []int
+	StartDate string
+}
+
+// System represents a star system in the galaxy.
+type System struct {
+	ID          int
+	Name        string
+	Type        string
+	StarClass   string
+	X           float64
+	Y           float64
+	PlanetIDs   []int
+	Hyperlanes  []Hyperlane
+	StarbaseIDs []int
+	// OwnerID is the owner of the first starbase of the system or [NoID] when it has no owner.
+	OwnerID int
+}
+
+// Hyperlane represents a hyperlane from a system to another system.
+type Hyperlane struct {
+	To     int // ID of the other system
+	Length float64
+}
+
+// Starbase represents a starbase, which claims a system for it's owner.
+type Starbase struct {
+	ID        int
+	Level     string
+	StationID int // ID of the ship of the starbase
+	OwnerID   int
+}
+
+// Bypass represents a connection between systems other than hyperlanes, e.g. a wormhole or a gateway.
+type Bypass struct {
+	ID         int
+	Type       string // e.g. "wormhole" or "gateway"
+	Active     bool
+	SystemID   int
+	LinkedToID int // ID of the linked bypass or [NoID] when it is not linked
+}
+
+// systemType is the type of references to systems.
+const systemType = 6
+
+// NewGamestate returns the typed model of a parsed gamestate.
+func NewGamestate(data map[string][]any) *Gamestate {
+	gs := &Gamestate{
+		Date: getString(data, "date"),
+		Countries: entities(data, "country", func(id int, o map[string][]any) *Country {
+			return &Country{
+				ID:               id,
+				Name:             getName(o, "name"),
+				Adjective:        getName(o, "adjective"),
+				Type:             getString(o, "type"),
+				Personality:      getString(o, "personality"),
+				CapitalID:        getID(o, "capital"),
+				FounderSpeciesID: getID(o, "founder_species_ref"),
+				RulerID:          getID(o, "ruler"),
+				FederationID:     getID(o, "federation"),
+				MilitaryPower:    getNumber(o, "military_power"),
+				EconomyPower:     getNumber(o, "economy_power"),
+				TechPower:        getNumber(o, "tech_power"),
+				VictoryRank:      int(getNumber(o, "victory_rank")),
+				VictoryScore:     getNumber(o, "victory_score"),
+				FleetSize:        int(getNumber(o, "fleet_size")),
+				EmpireSize:       int(getNumber(o, "empire_size")),
+				OwnedPlanetIDs:   getIDs(o, "owned_planets"),
+				Income:           income(o),
+				TechCount:        techCount(o),
+				Relations:        relations(o),
+				FlagColors:       getStrings(o, "flag.colors"),
+			}
+		}),
+		Species: entities(data, "species_db", func(id int, o map[string][]any) *Species {
+			s := &Species{
+				ID:           id,
+				Name:         getName(o, "name"),
+				Plural:       getName(o, "plural"),
+				Adjective:    getName(o, "adjective"),
+				Class:        getString(o, "class"),
+				Portrait:     getString(o, "portrait"),
+				HomePlanetID: getID(o, "home_planet"),
+			}
+			if traits, ok := tree.Object(o, "traits"); ok {
+				for _, v := range traits["trait"] {
+					if t, ok := v.(string); ok {
+						s.Traits = append(s.Traits, t)
+					}
+				}
+			}
+			return s
+		}),
+		Planets: entities(data, "planets.planet", func(id int, o map[string][]any) *Planet {
+			return &Planet{
+				ID:              id,
+				Name:            getName(o, "name"),
+				Class:           getString(o, "planet_class"),
+				Size:            int(getNumber(o, "planet_size")),
+				SystemID:        getID(o, "coordinate.origin"),
+				OwnerID:         getID(o, "owner"),
+				ControllerID:    getID(o, "controller"),
+				OriginalOwnerID: getID(o, "original_owner"),
+				Stability:       getNumber(o, "stability"),
+				PopIDs:          getIDs(o, "pop"),
+			}
+		}),
+		Pops: entities(data, "pop", func(id int, o map[string][]any) *Pop {
+			speciesID := getID(o, "species")
+			if speciesID == NoID {
+				speciesID = getID(o, "species_index")
+			}
+			return &Pop{
+				ID:        id,
+				SpeciesID: speciesID,
+				PlanetID:  getID(o, "planet"),
+				Job:       getString(o, "job"),
+				Category:  getString(o, "category"),
+			}
+		}),
+		Leaders: entities(data, "leaders", func(id int, o map[string][]any) *Leader {
+			speciesID := getID(o, "species")
+			if speciesID == NoID {
+				speciesID = getID(o, "species_index")
+			}
+			return &Leader{
+				ID:        id,
+				Name:      getName(o, "name"),
+				Class:     getString(o, "class"),
+				Level:     int(getNumber(o, "level")),
+				Age:       int(getNumber(o, "age")),
+				SpeciesID: speciesID,
+				CountryID: getID(o, "country"),
+			}
+		}),
+		Fleets: entities(data, "fleet", func(id int, o map[string][]any) *Fleet {
+			systemID := getID(o, "combat.coordinate.origin")
+			if systemID == NoID {
+				systemID = getID(o, "movement_manager.coordinate.origin")
+			}
+			isStation, _ := get[bool](o, "station")
+			isCivilian, _ := get[bool](o, "civilian")
+			return &Fleet{
+				ID:            id,
+				Name:          getName(o, "name"),
+				OwnerID:       getID(o, "owner"),
+				SystemID:      systemID,
+				MilitaryPower: getNumber(o, "military_power"),
+				IsStation:     isStation,
+				IsCivilian:    isCivilian,
+				ShipIDs:       getIDs(o, "ships"),
+			}
+		}),
+		Ships: entities(data, "ships", func(id int, o map[string][]any) *Ship {
+			return &Ship{
+				ID:        id,
+				Name:      getName(o, "name"),
+				FleetID:   getID(o, "fleet"),
+				LeaderID:  getID(o, "leader"),
+				DesignID:  getID(o, "ship_design"),
+				Hitpoints: getNumber(o, "hitpoints"),
+			}
+		}),
+		Wars: entities(data, "war", func(id int, o map[string][]any) *War {
+			return &War{
+				ID:                    id,
+				Name:                  getName(o, "name"),
+				StartDate:             getString(o, "start_date"),
+				Attackers:             warParticipants(o["attackers"]),
+				Defenders:             warParticipants(o["defenders"]),
+				AttackerWarGoal:       getString(o, "attacker_war_goal.type"),
+				DefenderWarGoal:       getString(o, "defender_war_goal.type"),
+				AttackerWarExhaustion: getNumber(o, "attacker_war_exhaustion"),
+				DefenderWarExhaustion: getNumber(o, "defender_war_exhaustion"),
+				Battles:               battles(o["battles"]),
+			}
+		}),
+		Federations: entities(data, "federation", func(id int, o map[string][]any) *Federation {
+			return &Federation{
+				ID:        id,
+				Name:      getName(o, "name"),
+				Type:      getString(o, "federation_progression.federation_type"),
+				Level:     int(getNumber(o, "federation_progression.level")),
+				LeaderID:  getID(o, "leader"),
+				MemberIDs: getIDs(o, "members"),
+				StartDate: getString(o, "start_date"),
+			}
+		}),
+		Systems: entities(data, "galactic_object", func(id int, o map[string][]any) *System {
+			s := &System{
+				ID:          id,
+				Name:        getName(o, "name"),
+				Type:        getString(o, "type"),
+				StarClass:   getString(o, "star_class"),
+				X:           getNumber(o, "coordinate.x"),
+				Y:           getNumber(o, "coordinate.y"),
+				PlanetIDs:   getAllIDs(o, "planet"),
+				StarbaseIDs: getAllIDs(o, "starbases"),
+				OwnerID:     NoID,
+			}
+			for _, h := range tree.Objects(o["hyperlane"]) {
+				s.Hyperlanes = append(s.Hyperlanes, Hyperlane{To: getID(h, "to"), Length: getNumber(h, "length")})
+			}
+			return s
+		}),
+		Starbases: entities(data, "starbase_mgr.starbases", func(id int, o map[string][]any) *Starbase {
+			return &Starbase{
+				ID:        id,
+				Level:     getString(o, "level"),
+				StationID: getID(o, "station"),
+				OwnerID:   getID(o, "owner"),
+			}
+		}),
+		Bypasses: entities(data, "bypasses", func(id int, o map[string][]any) *Bypass {
+			b := &Bypass{
+				ID:         id,
+				Type:       getString(o, "type"),
+				SystemID:   NoID,
+				LinkedToID: getID(o, "linked_to"),
+			}
+			b.Active, _ = get[bool](o, "active")
+			if getNumber(o, "owner.type") == systemType {
+				b.SystemID = getID(o, "owner.id")
+			}
+			return b
+		}),
+	}
+	for _, s := range gs.Systems {
+		for _, id := range s.StarbaseIDs {
+			if sb, ok := gs.Starbases[id]; ok && sb.OwnerID != NoID {
+				s.OwnerID = sb.OwnerID
+				break
+			}
+		}
+	}
+	return gs
+}
+
+// entities returns the entities of the collection at the dotted path in data
+// created with newEntity.
+func entities[T any](data map[string][]any, path string, newEntity func(id int, o map[string][]any) *T) map[int]*T {
+	r := make(map[int]*T)
+	m, ok := tree.Object(data, path)
+	if !ok {
+		return r
+	}
+	for k := range m {
+		o, ok := tree.Object(m, k)
+		if !ok {
+			continue // entity was deleted
+		}
+		id, err := strconv.Atoi(k)
+		if err != nil {
+			continue
+		}
+		r[id] = newEntity(id, o)
+	}
+	return r
+}
+
+// income returns the sum of the monthly income of a country by resource.
+func income(o map[string][]any) map[string]float64 {
+	r := make(map[string]float64)
+	sources, _ := tree.Object(o, "budget.current_month.income")
+	for _, vv := range sources {
+		for _, source := range tree.Objects(vv) {
+			for resource, values := range source {
+				for _, v := range values {
+					if x, ok := v.(float64); ok {
+						r[resource] += x
+					}
+				}
+			}
+		}
+	}
+	return r
+}
+
+func techCount(o map[string][]any) int {
+	ts, ok := tree.Object(o, "tech_status")
+	if !ok {
+		return 0
+	}
+	var n int
+	for _, v := range ts["technology"] {
+		switch x := v.(type) {
+		case string:
+			n++
+		case []string:
+			n += len(x)
+		}
+	}
+	return n
+}
+
+func relations(o map[string][]any) []Relation {
+	rm, ok := tree.Object(o, "relations_manager")
+	if !ok {
+		return nil
+	}
+	var r []Relation
+	for _, x := range tree.Objects(rm["relation"]) {
+		rel := Relation{CountryID: getID(x, "country"), Opinion: getNumber(x, "relation_current")}
+		for _, k := range tree.Keys(x) {
+			if b, _ := get[bool](x, k); b {
+				rel.Statuses = append(rel.Statuses, k)
+			}
+		}
+		r = append(r, rel)
+	}
+	return r
+}
+
+func battles(vv []any) []Battle {
+	var r []Battle
+	for _, o := range tree.Objects(vv) {
+		b := Battle{
+			Date:                  getString(o, "date"),
+			Type:                  getString(o, "type"),
+			SystemID:              getID(o, "system"),
+			PlanetID:              getID(o, "planet"),
+			AttackerIDs:           getAllIDs(o, "attackers"),
+			DefenderIDs:           getAllIDs(o, "defenders"),
+			AttackerWarExhaustion: getNumber(o, "attacker_war_exhaustion"),
+			DefenderWarExhaustion: getNumber(o, "defender_war_exhaustion"),
+			AttackerLosses:        getNumber(o, "attacker_losses"),
+			DefenderLosses:        getNumber(o, "defender_losses"),
+		}
+		b.AttackerVictory, _ = get[bool](o, "attacker_victory")
+		r = append(r, b)
+	}
+	return r
+}
+
+func warParticipants(vv []any) []WarParticipant {
+	var r []WarParticipant
+	for _, o := range tree.Objects(vv) {
+		r = append(r, WarParticipant{CountryID: getID(o, "country"), CallType: getString(o, "call_type")})
+	}
+	return r
+}
+
+func getName(m map[string][]any, path string) string {
+	v, _ := tree.Get(m, path)
+	return tree.Name(v)
+}
+
+// getID returns the reference at the dotted path in m or [NoID] when it is not set.
+func getID(m map[string][]any, path string) int {
+	x, ok := get[float64](m, path)
+	if !ok || x == unsetID {
+		return NoID
+	}
+	return int(x)
+}
+
+// getAllIDs returns all references which are set from the key at the dotted path in m,
+// which can be repeated, e.g. planet=1 planet=4, or a list, e.g. starbases={ 0 }.
+func getAllIDs(m map[string][]any, path string) []int {
+	parent, key := m, path
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		o, ok := tree.Object(m, path[:i])
+		if !ok {
+			return nil
+		}
+		parent, key = o, path[i+1:]
+	}
+	var ids []int
+	add := func(x float64) {
+		if id, ok := ParseID(x); ok {
+			ids = append(ids, id)
+		}
+	}
+	for _, v := range parent[key] {
+		switch x := v.(type) {
+		case float64:
+			add(x)
+		case []float64:
+			for _, y := range x {
+				add(y)
+			}
+		}
+	}
+	return ids
+}
+
+func getIDs(m map[string][]any, path string) []int {
+	s, _ := get[[]float64](m, path)
+	var ids []int
+	for _, x := range s {
+		ids = append(ids, int(x))
+	}
+	return ids
+}
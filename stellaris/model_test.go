@@ -0,0 +1,136 @@
+package stellaris_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+func TestNewGamestate(t *testing.T) {
+	gs := stellaris.NewGamestate(testutil.LoadGamestate(t))
+	t.Run("should create date", func(t *testing.T) {
+		assert.Equal(t, "2415.06.06", gs.Date)
+	})
+	t.Run("should create all collections without deleted entities", func(t *testing.T) {
+		assert.Len(t, gs.Countries, 3)
+		assert.Len(t, gs.Species, 3)
+		assert.Len(t, gs.Planets, 5)
+		assert.Len(t, gs.Pops, 4)
+		assert.Len(t, gs.Leaders, 2)
+		assert.Len(t, gs.Fleets, 5)
+		assert.Len(t, gs.Ships, 6)
+		assert.Len(t, gs.Wars, 1)
+		assert.Len(t, gs.Federations, 1)
+		assert.Len(t, gs.Systems, 6)
+		assert.Len(t, gs.Starbases, 3)
+		assert.Len(t, gs.Bypasses, 2)
+	})
+	t.Run("should create countries", func(t *testing.T) {
+		c := gs.Countries[0]
+		assert.Equal(t, "Blooms of Gaea", c.Name)
+		assert.Equal(t, 1, c.CapitalID)
+		assert.Equal(t, 0, c.FederationID)
+		assert.Equal(t, 12500.5, c.MilitaryPower)
+		assert.Equal(t, []int{1, 4}, c.OwnedPlanetIDs)
+		assert.Equal(t, stellaris.NoID, gs.Countries[2].FederationID)
+	})
+	t.Run("should create economy and technology of countries", func(t *testing.T) {
+		c := gs.Countries[0]
+		assert.Equal(t, 120.5, c.Income["energy"])
+		assert.Equal(t, 90.0, c.Income["minerals"])
+		assert.Equal(t, 40.0, c.Income["physics_research"])
+		assert.Equal(t, 4, c.TechCount)
+		assert.Equal(t, 1, gs.Countries[2].TechCount)
+	})
+	t.Run("should create relations of countries", func(t *testing.T) {
+		c := gs.Countries[0]
+		require.Len(t, c.Relations, 2)
+		assert.Equal(t, stellaris.Relation{CountryID: 1, Opinion: 120, Statuses: []string{"alliance", "communications"}}, c.Relations[0])
+		assert.True(t, c.Relations[1].HasStatus("hostile"))
+		assert.False(t, c.Relations[1].HasStatus("alliance"))
+	})
+	t.Run("should create planets", func(t *testing.T) {
+		p := gs.Planets[2]
+		assert.Equal(t, "NAME_Earth", p.Name)
+		assert.Equal(t, "pc_continental", p.Class)
+		assert.Equal(t, 1, p.SystemID)
+		assert.Equal(t, 1, p.OwnerID)
+		assert.Equal(t, []int{2}, p.PopIDs)
+		assert.Equal(t, stellaris.NoID, gs.Planets[5].OwnerID)
+	})
+	t.Run("should create species", func(t *testing.T) {
+		s := gs.Species[0]
+		assert.Equal(t, "Gaeans", s.Name)
+		assert.Equal(t, []string{"trait_phototrophic", "trait_rapid_breeders"}, s.Traits)
+	})
+	t.Run("should create fleets and ships", func(t *testing.T) {
+		f := gs.Fleets[1]
+		assert.Equal(t, "Bloom Fleet", f.Name)
+		assert.Equal(t, []int{20, 21}, f.ShipIDs)
+		assert.Equal(t, 1, f.SystemID)
+		assert.True(t, gs.Fleets[0].IsStation)
+		assert.Equal(t, 0, gs.Ships[20].LeaderID)
+		assert.Equal(t, stellaris.NoID, gs.Ships[21].LeaderID)
+	})
+	t.Run("should create wars", func(t *testing.T) {
+		w := gs.Wars[0]
+		assert.Equal(t, "Gaean-Kel War", w.Name)
+		assert.Equal(t, []stellaris.WarParticipant{{0, "primary"}, {1, "alliance"}}, w.Attackers)
+		assert.Equal(t, []stellaris.WarParticipant{{2, "primary"}}, w.Defenders)
+		assert.Equal(t, "wg_humiliation", w.AttackerWarGoal)
+		require.Len(t, w.Battles, 2)
+		assert.Equal(t, stellaris.Battle{
+			Date:                  "2405.02.10",
+			Type:                  "armies",
+			SystemID:              0,
+			PlanetID:              4,
+			AttackerIDs:           []int{2},
+			DefenderIDs:           []int{0},
+			AttackerVictory:       false,
+			AttackerWarExhaustion: 1.5,
+			DefenderWarExhaustion: 0.5,
+			AttackerLosses:        3,
+			DefenderLosses:        1,
+		}, w.Battles[1])
+		assert.True(t, w.Battles[0].AttackerVictory)
+		assert.Equal(t, stellaris.NoID, w.Battles[0].PlanetID)
+	})
+	t.Run("should create systems", func(t *testing.T) {
+		s := gs.Systems[0]
+		assert.Equal(t, "Gaea", s.Name)
+		assert.Equal(t, "sc_g", s.StarClass)
+		assert.Equal(t, 0.0, s.X)
+		assert.Equal(t, 0.0, s.Y)
+		assert.Equal(t, []int{1, 4}, s.PlanetIDs)
+		assert.Equal(t, []stellaris.Hyperlane{{To: 1, Length: 30}, {To: 2, Length: 45}}, s.Hyperlanes)
+		assert.Equal(t, []int{0}, s.StarbaseIDs)
+		assert.Equal(t, 0, s.OwnerID)
+		assert.Equal(t, 2, gs.Systems[2].OwnerID)
+		assert.Empty(t, gs.Systems[3].StarbaseIDs)
+		assert.Equal(t, stellaris.NoID, gs.Systems[3].OwnerID)
+		assert.Empty(t, gs.Systems[5].Hyperlanes)
+	})
+	t.Run("should create starbases", func(t *testing.T) {
+		sb := gs.Starbases[1]
+		assert.Equal(t, "starbase_level_starport", sb.Level)
+		assert.Equal(t, 11, sb.StationID)
+		assert.Equal(t, 1, sb.OwnerID)
+	})
+	t.Run("should create bypasses", func(t *testing.T) {
+		assert.Equal(t, &stellaris.Bypass{ID: 0, Type: "wormhole", Active: true, SystemID: 4, LinkedToID: 1}, gs.Bypasses[0])
+	})
+	t.Run("should create flag colors of countries", func(t *testing.T) {
+		assert.Equal(t, []string{"dark_red", "orange", "null", "null"}, gs.Countries[2].FlagColors)
+	})
+	t.Run("should create federations", func(t *testing.T) {
+		f := gs.Federations[0]
+		assert.Equal(t, "Green Alliance", f.Name)
+		assert.Equal(t, "research_federation", f.Type)
+		assert.Equal(t, 2, f.Level)
+		assert.Equal(t, []int{0, 1}, f.MemberIDs)
+	})
+}
@@ -0,0 +1,285 @@
+package stellaris
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+)
+
+// Reference describes where entities of a collection reference entities of another collection.
+type Reference struct {
+	Section string // dotted path to the referencing collection, e.g. "planets.planet"
+	Path    string // dotted path to the reference in the referencing entities, e.g. "owner". Alternatives can be separated by "|".
+	Target  string // dotted path to the referenced collection, e.g. "country"
+}
+
+// References are the known references between the major collections of the gamestate.
+//
+// References are identified by the collection and path of their key,
+// because the same key can point to different collections,
+// e.g. the leader of a ship is a leader, but the leader of a federation is a country.
+var References = []Reference{
+	{"country", "capital", "planets.planet"},
+	{"country", "founder_species_ref", "species_db"},
+	{"country", "ruler", "leaders"},
+	{"country", "owned_planets", "planets.planet"},
+	{"species_db", "home_planet", "planets.planet"},
+	{"planets.planet", "owner", "country"},
+	{"planets.planet", "controller", "country"},
+	{"planets.planet", "original_owner", "country"},
+	{"pop", "species|species_index", "species_db"},
+	{"pop", "planet", "planets.planet"},
+	{"leaders", "species|species_index", "species_db"},
+	{"leaders", "country", "country"},
+	{"fleet", "owner", "country"},
+	{"fleet", "ships", "ships"},
+	{"ships", "fleet", "fleet"},
+	{"ships", "leader", "leaders"},
+	{"war", "attackers.country", "country"},
+	{"war", "defenders.country", "country"},
+	{"war", "battles.attackers", "country"},
+	{"war", "battles.defenders", "country"},
+	{"war", "battles.planet", "planets.planet"},
+	{"federation", "members", "country"},
+	{"federation", "leader", "country"},
+	{"galactic_object", "planet", "planets.planet"},
+	{"starbase_mgr.starbases", "owner", "country"},
+	{"starbase_mgr.starbases", "station", "ships"},
+}
+
+// Resolver resolves references between the collections of a parsed gamestate.
+//
+// It indexes the entities of the major [Collections] by their ID.
+// Deleted entities are not included.
+type Resolver struct {
+	gamestate map[string][]any
+	indexes   map[string]map[int]map[string][]any // entities by ID for each collection
+}
+
+// NewResolver returns a new resolver for a parsed gamestate.
+func NewResolver(gamestate map[string][]any) *Resolver {
+	r := &Resolver{gamestate: gamestate, indexes: make(map[string]map[int]map[string][]any)}
+	for _, c := range Collections {
+		index := make(map[int]map[string][]any)
+		m, _ := tree.Object(gamestate, c.Section)
+		for k := range m {
+			o, ok := tree.Object(m, k)
+			if !ok {
+				continue // entity was deleted
+			}
+			if id, ok := ParseID(k); ok {
+				index[id] = o
+			}
+		}
+		r.indexes[c.Section] = index
+	}
+	return r
+}
+
+// Lookup returns the entity with the ID from the collection at section, e.g. "country",
+// and reports whether it was found.
+func (r *Resolver) Lookup(section string, id int) (map[string][]any, bool) {
+	o, ok := r.indexes[section][id]
+	return o, ok
+}
+
+// Country returns the country with the ID and reports whether it was found.
+func (r *Resolver) Country(id int) (map[string][]any, bool) {
+	return r.Lookup("country", id)
+}
+
+// Planet returns the planet with the ID and reports whether it was found.
+func (r *Resolver) Planet(id int) (map[string][]any, bool) {
+	return r.Lookup("planets.planet", id)
+}
+
+// Species returns the species with the ID and reports whether it was found.
+func (r *Resolver) Species(id int) (map[string][]any, bool) {
+	return r.Lookup("species_db", id)
+}
+
+// Leader returns the leader with the ID and reports whether it was found.
+func (r *Resolver) Leader(id int) (map[string][]any, bool) {
+	return r.Lookup("leaders", id)
+}
+
+// Fleet returns the fleet with the ID and reports whether it was found.
+func (r *Resolver) Fleet(id int) (map[string][]any, bool) {
+	return r.Lookup("fleet", id)
+}
+
+// Ship returns the ship with the ID and reports whether it was found.
+func (r *Resolver) Ship(id int) (map[string][]any, bool) {
+	return r.Lookup("ships", id)
+}
+
+// Name returns the name of the entity with the ID from the collection at section
+// and reports whether the entity was found and has a name.
+func (r *Resolver) Name(section string, id int) (string, bool) {
+	o, ok := r.Lookup(section, id)
+	if !ok {
+		return "", false
+	}
+	s := getName(o, "name")
+	return s, s != ""
+}
+
+// InlineNames adds the names of referenced entities to the gamestate of the resolver.
+//
+// For each of the [References] the name is added next to the reference
+// with the suffix "_name", e.g. owner_name="Blooms of Gaea" for owner=0.
+// Lists of references get a list of names with the suffix "_names", e.g. for members={ 0 1 }.
+// Repeated references get a name for each value.
+// References to entities which do not exist or have no name are skipped.
+func (r *Resolver) InlineNames() {
+	r.walkReferences(func(ref Reference, _ string, o map[string][]any, key string) {
+		r.inlineName(o, key, ref.Target)
+	})
+}
+
+// DanglingReference represents a reference to an entity, which does not exist.
+type DanglingReference struct {
+	Reference
+	ID       string // ID of the referencing entity
+	TargetID int    // ID of the missing entity
+}
+
+func (d DanglingReference) String() string {
+	return fmt.Sprintf("%s.%s.%s: %s %d does not exist", d.Section, d.ID, d.Path, d.Target, d.TargetID)
+}
+
+// DanglingReferences returns all references of the gamestate to entities, which do not exist.
+// This includes references to deleted entities.
+// References which are not set are ignored.
+//
+// Dangling references are reported in the order of the [References] and the IDs of the referencing entities.
+func (r *Resolver) DanglingReferences() []DanglingReference {
+	var dd []DanglingReference
+	r.walkReferences(func(ref Reference, id string, o map[string][]any, key string) {
+		check := func(v any) {
+			targetID, ok := ParseID(v)
+			if !ok {
+				return
+			}
+			if _, found := r.Lookup(ref.Target, targetID); !found {
+				dd = append(dd, DanglingReference{Reference: ref, ID: id, TargetID: targetID})
+			}
+		}
+		for _, v := range o[key] {
+			switch x := v.(type) {
+			case float64:
+				check(x)
+			case []float64:
+				for _, y := range x {
+					check(y)
+				}
+			}
+		}
+	})
+	return dd
+}
+
+// walkReferences calls fn with each object containing the key of one of the [References]
+// and the ID of the referencing entity.
+// For references with alternative paths only the first path found in an entity is used
+// and fn is called with a reference for that path.
+func (r *Resolver) walkReferences(fn func(ref Reference, id string, o map[string][]any, key string)) {
+	for _, ref := range References {
+		m, ok := tree.Object(r.gamestate, ref.Section)
+		if !ok {
+			continue
+		}
+		for _, id := range tree.IDs(m) {
+			for _, o := range tree.Objects(m[id]) {
+				for _, path := range strings.Split(ref.Path, "|") {
+					parent, key := "", path
+					if i := strings.LastIndex(path, "."); i >= 0 {
+						parent, key = path[:i], path[i+1:]
+					}
+					found := false
+					for _, p := range objectsAt(o, parent) {
+						if _, ok := p[key]; ok {
+							fn(Reference{ref.Section, path, ref.Target}, id, p, key)
+							found = true
+						}
+					}
+					if found {
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func (r *Resolver) inlineName(o map[string][]any, key, target string) {
+	_, hasName := o[key+"_name"]
+	_, hasNames := o[key+"_names"]
+	if hasName || hasNames {
+		return // never overwrite existing keys
+	}
+	for _, v := range o[key] {
+		switch x := v.(type) {
+		case float64:
+			if s, ok := r.refName(target, x); ok {
+				o[key+"_name"] = append(o[key+"_name"], s)
+			}
+		case []float64:
+			var names []string
+			for _, y := range x {
+				if s, ok := r.refName(target, y); ok {
+					names = append(names, s)
+				}
+			}
+			if len(names) > 0 {
+				o[key+"_names"] = append(o[key+"_names"], names)
+			}
+		}
+	}
+}
+
+func (r *Resolver) refName(target string, v any) (string, bool) {
+	id, ok := ParseID(v)
+	if !ok {
+		return "", false
+	}
+	return r.Name(target, id)
+}
+
+// objectsAt returns all objects at the dotted path in o.
+// Other than [tree.Get] it includes all values of repeated keys and arrays of objects.
+func objectsAt(o map[string][]any, path string) []map[string][]any {
+	oo := []map[string][]any{o}
+	if path == "" {
+		return oo
+	}
+	for _, k := range strings.Split(path, ".") {
+		var next []map[string][]any
+		for _, x := range oo {
+			next = append(next, tree.Objects(x[k])...)
+		}
+		oo = next
+	}
+	return oo
+}
+
+// ParseID returns the ID of a reference from a parsed value
+// and reports whether it is a reference, which is set.
+func ParseID(v any) (int, bool) {
+	switch x := v.(type) {
+	case float64:
+		if x < 0 || x == unsetID || x != float64(int(x)) {
+			return NoID, false
+		}
+		return int(x), true
+	case string:
+		id, err := strconv.Atoi(x)
+		if err != nil || id < 0 || float64(id) == unsetID {
+			return NoID, false
+		}
+		return id, true
+	}
+	return NoID, false
+}
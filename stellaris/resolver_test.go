@@ -0,0 +1,163 @@
+package stellaris_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+func TestResolver(t *testing.T) {
+	r := stellaris.NewResolver(testutil.LoadGamestate(t))
+	t.Run("should lookup entities", func(t *testing.T) {
+		c, ok := r.Country(2)
+		require.True(t, ok)
+		assert.Equal(t, []any{15000.0}, c["military_power"])
+		p, ok := r.Planet(1)
+		require.True(t, ok)
+		assert.Equal(t, []any{"pc_gaia"}, p["planet_class"])
+		_, ok = r.Species(0)
+		assert.True(t, ok)
+		_, ok = r.Leader(0)
+		assert.True(t, ok)
+		_, ok = r.Fleet(1)
+		assert.True(t, ok)
+		_, ok = r.Ship(20)
+		assert.True(t, ok)
+	})
+	t.Run("should not find deleted or unknown entities", func(t *testing.T) {
+		_, ok := r.Country(3)
+		assert.False(t, ok)
+		_, ok = r.Country(99)
+		assert.False(t, ok)
+		_, ok = r.Lookup("unknown", 0)
+		assert.False(t, ok)
+	})
+	t.Run("should return names", func(t *testing.T) {
+		s, ok := r.Name("country", 0)
+		assert.True(t, ok)
+		assert.Equal(t, "Blooms of Gaea", s)
+		s, ok = r.Name("leaders", 0)
+		assert.True(t, ok)
+		assert.Equal(t, "Jorg Xu", s)
+		_, ok = r.Name("pop", 0)
+		assert.False(t, ok)
+	})
+}
+
+func TestResolverInlineNames(t *testing.T) {
+	data := testutil.LoadGamestate(t)
+	stellaris.NewResolver(data).InlineNames()
+	get := func(path string) any {
+		v, _ := tree.Get(data, path)
+		return v
+	}
+	t.Run("should add names of single references", func(t *testing.T) {
+		assert.Equal(t, "Blooms of Gaea", get("planets.planet.1.owner_name"))
+		assert.Equal(t, "Gaea", get("country.0.capital_name"))
+		assert.Equal(t, "Jorg Xu", get("ships.20.leader_name"))
+		assert.Equal(t, "Kel-Azaan Hegemony", get("starbase_mgr.starbases.2.owner_name"))
+	})
+	t.Run("should add names of lists of references", func(t *testing.T) {
+		assert.Equal(t, []string{"Blooms of Gaea", "SPEC_Human_adj Commonwealth"}, get("federation.0.members_names"))
+		assert.Equal(t, []string{"Gaea", "Gaea II"}, get("country.0.owned_planets_names"))
+	})
+	t.Run("should add names of references in nested objects", func(t *testing.T) {
+		w, ok := tree.Object(data, "war.0")
+		require.True(t, ok)
+		attackers := tree.Objects(w["attackers"])
+		require.Len(t, attackers, 2)
+		assert.Equal(t, []any{"SPEC_Human_adj Commonwealth"}, attackers[1]["country_name"])
+		battles := tree.Objects(w["battles"])
+		require.Len(t, battles, 2)
+		assert.Equal(t, []any{[]string{"Kel-Azaan Hegemony"}}, battles[0]["defenders_names"])
+		assert.Equal(t, []any{"Gaea II"}, battles[1]["planet_name"])
+	})
+	t.Run("should add names for repeated references", func(t *testing.T) {
+		o, ok := tree.Object(data, "galactic_object.0")
+		require.True(t, ok)
+		assert.Equal(t, []any{"Gaea", "Gaea II"}, o["planet_name"])
+	})
+	t.Run("should skip references which are not set", func(t *testing.T) {
+		o, ok := tree.Object(data, "ships.21")
+		require.True(t, ok)
+		assert.NotContains(t, o, "leader_name")
+	})
+	t.Run("should fall back to species index of pops", func(t *testing.T) {
+		data := testutil.LoadGamestate(t)
+		o, ok := tree.Object(data, "pop.3")
+		require.True(t, ok)
+		delete(o, "species")
+		o["species_index"] = []any{1.0}
+		r := stellaris.NewResolver(data)
+		r.InlineNames()
+		want, ok := r.Name("species_db", 1)
+		require.True(t, ok)
+		assert.Equal(t, []any{want}, o["species_index_name"])
+	})
+}
+
+func TestParseID(t *testing.T) {
+	cases := []struct {
+		v    any
+		id   int
+		isOK bool
+	}{
+		{3.0, 3, true},
+		{"42", 42, true},
+		{"4294967295", stellaris.NoID, false},
+		{4294967295.0, stellaris.NoID, false},
+		{-1.0, stellaris.NoID, false},
+		{1.5, stellaris.NoID, false},
+		{"alpha", stellaris.NoID, false},
+		{true, stellaris.NoID, false},
+	}
+	for _, tc := range cases {
+		id, ok := stellaris.ParseID(tc.v)
+		assert.Equal(t, tc.id, id, tc.v)
+		assert.Equal(t, tc.isOK, ok, tc.v)
+	}
+}
+
+func TestResolverDanglingReferences(t *testing.T) {
+	t.Run("should report nothing for valid gamestate", func(t *testing.T) {
+		r := stellaris.NewResolver(testutil.LoadGamestate(t))
+		assert.Empty(t, r.DanglingReferences())
+	})
+	t.Run("should report dangling references", func(t *testing.T) {
+		data := testutil.LoadGamestate(t)
+		set := func(path, key string, v any) {
+			o, ok := tree.Object(data, path)
+			require.True(t, ok, path)
+			o[key] = []any{v}
+		}
+		set("ships.21", "fleet", 99.0)
+		set("planets.planet.2", "owner", 3.0) // country 3 was deleted
+		set("pop.2", "species", 7.0)
+		pop, ok := tree.Object(data, "pop.3")
+		require.True(t, ok)
+		delete(pop, "species")
+		set("pop.3", "species_index", 8.0)
+		set("federation.0", "members", []float64{0, 1, 5})
+		set("planets.planet.4", "controller", 4294967295.0) // not set
+		got := stellaris.NewResolver(data).DanglingReferences()
+		var ss []string
+		for _, d := range got {
+			ss = append(ss, d.String())
+		}
+		assert.Equal(t, []string{
+			"planets.planet.2.owner: country 3 does not exist",
+			"pop.2.species: species_db 7 does not exist",
+			"pop.3.species_index: species_db 8 does not exist",
+			"ships.21.fleet: fleet 99 does not exist",
+			"federation.0.members: country 5 does not exist",
+		}, ss)
+		assert.Equal(t, "owner", got[0].Path)
+		assert.Equal(t, "2", got[0].ID)
+		assert.Equal(t, 3, got[0].TargetID)
+	})
+}
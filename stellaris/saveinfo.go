@@ -0,0 +1,174 @@
+// Package stellaris provides access to Stellaris save games.
+package stellaris
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/parser"
+	"github.com/ErikKalkoken/stellaris-tool/internal/tree"
+)
+
+// Names of the files in a save game.
+const (
+	MetaFile      = "meta"
+	GamestateFile = "gamestate"
+)
+
+// ErrNoMeta is returned when a save game has no meta file.
+var ErrNoMeta = errors.New("save game has no meta file")
+
+var versionNumberPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// SaveInfo represents the information about a save game from it's meta file.
+type SaveInfo struct {
+	Version        string   `json:"version"` // e.g. "Andromeda v3.12.5"
+	Name           string   `json:"name"`    // name of the player's empire
+	Date           string   `json:"date"`    // in-game date, e.g. "2415.06.06"
+	RequiredDLCs   []string `json:"required_dlcs"`
+	Ironman        bool     `json:"ironman"`
+	PlayerPortrait string   `json:"player_portrait"`
+	Flag           Flag     `json:"flag"`
+	Fleets         int      `json:"fleets"`  // number of fleets
+	Planets        int      `json:"planets"` // number of planets
+}
+
+// Flag represents the flag of an empire.
+type Flag struct {
+	Icon       FlagImage `json:"icon"`
+	Background FlagImage `json:"background"`
+	Colors     []string  `json:"colors"`
+}
+
+// FlagImage represents an image of a flag.
+type FlagImage struct {
+	Category string `json:"category"`
+	File     string `json:"file"`
+}
+
+// VersionNumber returns the number of the game version, e.g. "3.12.5"
+// or an empty string when the version has no number.
+func (si SaveInfo) VersionNumber() string {
+	return versionNumberPattern.FindString(si.Version)
+}
+
+// CompareDates compares two in-game dates, e.g. "2415.06.06",
+// and returns -1 if a is before b, 1 if a is after b and 0 if they are equal.
+// Invalid dates are before all valid dates.
+func CompareDates(a, b string) int {
+	x, okA := parseDate(a)
+	y, okB := parseDate(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	return slices.Compare(x, y)
+}
+
+// parseDate returns the year, month and day of an in-game date.
+func parseDate(s string) ([]int, bool) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+	r := make([]int, 3)
+	for i, p := range parts {
+		x, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		r[i] = x
+	}
+	return r, true
+}
+
+// NewSaveInfo returns the information about a save game from it's parsed meta file.
+func NewSaveInfo(meta map[string][]any) SaveInfo {
+	si := SaveInfo{
+		Version:        getString(meta, "version"),
+		Name:           getString(meta, "name"),
+		Date:           getString(meta, "date"),
+		RequiredDLCs:   getStrings(meta, "required_dlcs"),
+		PlayerPortrait: getString(meta, "player_portrait"),
+		Fleets:         int(getNumber(meta, "meta_fleets")),
+		Planets:        int(getNumber(meta, "meta_planets")),
+		Flag: Flag{
+			Icon: FlagImage{
+				Category: getString(meta, "flag.icon.category"),
+				File:     getString(meta, "flag.icon.file"),
+			},
+			Background: FlagImage{
+				Category: getString(meta, "flag.background.category"),
+				File:     getString(meta, "flag.background.file"),
+			},
+			Colors: getStrings(meta, "flag.colors"),
+		},
+	}
+	si.Ironman, _ = get[bool](meta, "ironman")
+	return si
+}
+
+// ReadSaveInfo returns the information about the save game at path.
+// Only the small meta file is parsed, which makes it fast even for large save games.
+func ReadSaveInfo(path string) (SaveInfo, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return SaveInfo{}, err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name != MetaFile {
+			continue
+		}
+		meta, err := parseZipFile(f)
+		if err != nil {
+			return SaveInfo{}, fmt.Errorf("%s: %w", MetaFile, err)
+		}
+		return NewSaveInfo(meta), nil
+	}
+	return SaveInfo{}, ErrNoMeta
+}
+
+func parseZipFile(f *zip.File) (map[string][]any, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return parser.NewParser(r).Parse()
+}
+
+// get returns the value at the dotted path in m with type T and reports whether it was found.
+func get[T any](m map[string][]any, path string) (T, bool) {
+	v, ok := tree.Get(m, path)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	x, ok := v.(T)
+	return x, ok
+}
+
+func getString(m map[string][]any, path string) string {
+	s, _ := get[string](m, path)
+	return s
+}
+
+func getNumber(m map[string][]any, path string) float64 {
+	x, _ := get[float64](m, path)
+	return x
+}
+
+func getStrings(m map[string][]any, path string) []string {
+	s, _ := get[[]string](m, path)
+	return s
+}
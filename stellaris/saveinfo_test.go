@@ -0,0 +1,94 @@
+package stellaris_test
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ErikKalkoken/stellaris-tool/internal/testutil"
+	"github.com/ErikKalkoken/stellaris-tool/stellaris"
+)
+
+func TestReadSaveInfo(t *testing.T) {
+	t.Run("should return info from meta file", func(t *testing.T) {
+		p := makeSaveFile(t, "meta", "gamestate")
+		si, err := stellaris.ReadSaveInfo(p)
+		require.NoError(t, err)
+		assert.Equal(t, "Andromeda v3.12.5", si.Version)
+		assert.Equal(t, "3.12.5", si.VersionNumber())
+		assert.Equal(t, "Blooms of Gaea 2", si.Name)
+		assert.Equal(t, "2415.06.06", si.Date)
+		assert.Contains(t, si.RequiredDLCs, "Utopia")
+		assert.Len(t, si.RequiredDLCs, 19)
+		assert.True(t, si.Ironman)
+		assert.Equal(t, "pla17", si.PlayerPortrait)
+		assert.Equal(t, stellaris.FlagImage{Category: "ornate", File: "flag_ornate_24.dds"}, si.Flag.Icon)
+		assert.Equal(t, []string{"toxic_green", "shadow_teal", "black", "null"}, si.Flag.Colors)
+		assert.Equal(t, 940, si.Fleets)
+		assert.Equal(t, 34, si.Planets)
+	})
+	t.Run("should return error when save has no meta file", func(t *testing.T) {
+		p := makeSaveFile(t, "gamestate")
+		_, err := stellaris.ReadSaveInfo(p)
+		assert.ErrorIs(t, err, stellaris.ErrNoMeta)
+	})
+	t.Run("should return error when file does not exist", func(t *testing.T) {
+		_, err := stellaris.ReadSaveInfo(filepath.Join(t.TempDir(), "missing.sav"))
+		assert.Error(t, err)
+	})
+}
+
+func TestSaveInfoVersionNumber(t *testing.T) {
+	cases := []struct {
+		version string
+		want    string
+	}{
+		{"Andromeda v3.12.5", "3.12.5"},
+		{"Orion v3.0", "3.0"},
+		{"", ""},
+	}
+	for _, tc := range cases {
+		si := stellaris.SaveInfo{Version: tc.version}
+		assert.Equal(t, tc.want, si.VersionNumber())
+	}
+}
+
+func TestCompareDates(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"2415.06.06", "2415.06.06", 0},
+		{"2415.06.06", "2415.06.07", -1},
+		{"2415.12.01", "2415.06.07", 1},
+		{"9999.01.01", "10000.01.01", -1},
+		{"", "2200.01.01", -1},
+		{"2200.01.01", "invalid", 1},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, stellaris.CompareDates(tc.a, tc.b), tc.a+" "+tc.b)
+	}
+}
+
+// makeSaveFile creates a save game with the given files from testdata and returns it's path.
+func makeSaveFile(t *testing.T, names ...string) string {
+	p := filepath.Join(t.TempDir(), "test.sav")
+	f, err := os.Create(p)
+	require.NoError(t, err)
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, n := range names {
+		data, err := os.ReadFile(testutil.Path(n))
+		require.NoError(t, err)
+		fw, err := w.Create(n)
+		require.NoError(t, err)
+		_, err = fw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return p
+}